@@ -0,0 +1,181 @@
+// Package audit records who changed which config values, for operators
+// answering prompts and commands that edit config.
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyFileEnv names the environment variable holding the path of the key
+// secret values are hashed with, which defaults to
+// ~/.config/require/audit.key.
+const KeyFileEnv = "REQUIRE_AUDIT_KEY_FILE"
+
+// Event is one line of an audit trail.
+type Event struct {
+	Time    time.Time   `json:"timestamp"`
+	User    string      `json:"user"`
+	Host    string      `json:"host"`
+	Key     string      `json:"key"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+	Hashed  bool        `json:"hashed,omitempty"`
+	Source  string      `json:"source"`
+	Command []string    `json:"command"`
+}
+
+// Sink receives audit events.
+type Sink interface {
+	Record(ev Event) error
+}
+
+// NewEvent returns an event for a change to key made by the current process
+// and OS user. The old and new values of secret keys are replaced by their
+// HMACs under the installation's key, see KeyFile, so the trail shows that
+// a secret changed, and whether two values match, without holding them or
+// letting anyone without the key guess them. If the key cannot be loaded
+// the values are left out.
+func NewEvent(key string, old, new interface{}, src string, secret bool) Event {
+	ev := Event{
+		Time:    time.Now().UTC(),
+		User:    currentUser(),
+		Key:     key,
+		Old:     old,
+		New:     new,
+		Source:  src,
+		Command: os.Args,
+	}
+	ev.Host, _ = os.Hostname()
+	if secret {
+		ev.Old, ev.New = nil, nil
+		if k, err := LoadKey(KeyFile()); err == nil {
+			ev.Old, ev.New, ev.Hashed = Hash(k, old), Hash(k, new), true
+		}
+	}
+	return ev
+}
+
+// Hash returns the hex HMAC-SHA256 of val's JSON encoding under key, or nil
+// for nil.
+func Hash(key []byte, val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyFile returns the path of the installation's hashing key:
+// $REQUIRE_AUDIT_KEY_FILE, or ~/.config/require/audit.key.
+func KeyFile() string {
+	if f := os.Getenv(KeyFileEnv); f != "" {
+		return f
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "require", "audit.key")
+}
+
+// LoadKey returns the key in the file at path. A file that does not exist
+// is created holding a new random key, readable only by its owner.
+func LoadKey(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := writeKey(path); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+		b, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) < 16 {
+		return nil, errors.New(path + " does not hold a hex encoded audit key")
+	}
+	return key, nil
+}
+
+// writeKey creates the file at path holding a new random key. It fails if
+// the file exists, so processes racing to create it agree on one key.
+func writeKey(path string) error {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Writer writes events to W as JSON lines.
+type Writer struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+func (w *Writer) Record(ev Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.W.Write(append(b, '\n'))
+	return err
+}
+
+// File appends events to the file at Path as JSON lines, creating it
+// readable only by its owner. The file is opened for every event so it can
+// be rotated underneath a running process.
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *File) Record(ev Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	w := &Writer{W: out}
+	if err := w.Record(ev); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
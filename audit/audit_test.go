@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestLoadKeyCreatesPrivateKeyOnce(t *testing.T) {
+	path := filepath.Join(tempDir(t), "config", "audit.key")
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Fatalf("key is %d bytes, want 32", len(key))
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("key file mode is %v, want 0600", fi.Mode().Perm())
+	}
+	again, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(key) {
+		t.Fatal("loading the key again returned a different key")
+	}
+}
+
+func TestLoadKeyRejectsGarbage(t *testing.T) {
+	path := filepath.Join(tempDir(t), "audit.key")
+	if err := ioutil.WriteFile(path, []byte("not a key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(path); err == nil {
+		t.Fatal("expected an error for a file not holding a key")
+	}
+}
+
+func TestHashIsKeyed(t *testing.T) {
+	a, b := []byte("0123456789abcdef"), []byte("fedcba9876543210")
+	if Hash(a, "hunter2") != Hash(a, "hunter2") {
+		t.Fatal("the same value hashed differently under one key")
+	}
+	if Hash(a, "hunter2") == Hash(a, "hunter3") {
+		t.Fatal("different values hashed the same")
+	}
+	if Hash(a, "hunter2") == Hash(b, "hunter2") {
+		t.Fatal("the same value hashed the same under different keys")
+	}
+	if Hash(a, nil) != nil {
+		t.Fatal("nil should hash to nil")
+	}
+}
+
+func TestNewEventHashesSecrets(t *testing.T) {
+	path := filepath.Join(tempDir(t), "audit.key")
+	old := os.Getenv(KeyFileEnv)
+	os.Setenv(KeyFileEnv, path)
+	t.Cleanup(func() { os.Setenv(KeyFileEnv, old) })
+
+	ev := NewEvent("password", "hunter2", "hunter3", "test", true)
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ev.Hashed || ev.Old != Hash(key, "hunter2") || ev.New != Hash(key, "hunter3") {
+		t.Fatalf("secret event not hashed under the installation key: %+v", ev)
+	}
+	ev = NewEvent("name", "a", "b", "test", false)
+	if ev.Hashed || ev.Old != "a" || ev.New != "b" {
+		t.Fatalf("plain event was changed: %+v", ev)
+	}
+}
+
+func TestNewEventDropsSecretsWithoutKey(t *testing.T) {
+	path := filepath.Join(tempDir(t), "audit.key")
+	if err := ioutil.WriteFile(path, []byte("not a key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := os.Getenv(KeyFileEnv)
+	os.Setenv(KeyFileEnv, path)
+	t.Cleanup(func() { os.Setenv(KeyFileEnv, old) })
+
+	ev := NewEvent("password", "hunter2", "hunter3", "test", true)
+	if ev.Old != nil || ev.New != nil || ev.Hashed {
+		t.Fatalf("secret values recorded without a key: %+v", ev)
+	}
+}
+
+func TestFileAppendsEvents(t *testing.T) {
+	path := filepath.Join(tempDir(t), "audit.log")
+	f := &File{Path: path}
+	for _, k := range []string{"a", "b"} {
+		if err := f.Record(NewEvent(k, nil, k, "test", false)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	var keys []string
+	sc := bufio.NewScanner(fh)
+	for sc.Scan() {
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, ev.Key)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("got events %v, want [a b]", keys)
+	}
+}
@@ -0,0 +1,83 @@
+package require
+
+import (
+	"context"
+
+	"github.com/codegangsta/cli"
+	"github.com/gofunct/require/option"
+)
+
+// Options returns the Enforcer's option registry. Options added to it are
+// bound to the Enforcer's own viper instance by CliFlags rather than to the
+// global one.
+func (e *Enforcer) Options() *option.Set {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.options == nil {
+		e.options = option.NewSet()
+	}
+	return e.options
+}
+
+// CliFlags adds opts to the Enforcer's options, binds them and returns the
+// flags for every option along with one for each requirement no option
+// covers, for use as a cli.App's or cli.Command's Flags. Required options
+// become requirements. Requirement flags are strings named after their
+// keys.
+func (e *Enforcer) CliFlags(opts ...*option.Option) ([]cli.Flag, error) {
+	set := e.Options()
+	if err := set.Add(opts...); err != nil {
+		return nil, err
+	}
+	reqs := e.requirements()
+	required := map[string]bool{}
+	for _, key := range reqs {
+		required[key] = true
+	}
+	for _, o := range set.Options() {
+		if o.Required && !required[o.Key] {
+			e.addRequirements(o.Key)
+			reqs = append(reqs, o.Key)
+		}
+	}
+	for _, key := range reqs {
+		if set.Lookup(key) != nil {
+			continue
+		}
+		if _, err := set.Define(new(string), key, "", "", "value of "+key, true); err != nil {
+			return nil, err
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := set.Bind(e.v); err != nil {
+		return nil, err
+	}
+	return set.Flags(), nil
+}
+
+// Before returns a cli.BeforeFunc that takes the values of the flags from
+// CliFlags parsed into c and then enforces the Enforcer's requirements,
+// prompting for any that no flag, environment variable or file set.
+func (e *Enforcer) Before() cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		e.bindFlags()
+		return e.InitContext(context.Background())
+	}
+}
+
+// bindFlags records the flags that were set as the source of their values
+// and publishes those values to readers.
+func (e *Enforcer) bindFlags() {
+	opts := e.Options().Options()
+	before := e.snapshot()
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, o := range opts {
+		if o.HasChanged() {
+			e.setSource(o.Key, "flag --"+o.Key)
+		}
+	}
+	e.commit(before)
+}
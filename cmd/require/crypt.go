@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/gofunct/require/secret"
+	"github.com/spf13/cobra"
+)
+
+// newKeyEnv names the environment variable rotate reads the new key from.
+const newKeyEnv = "REQUIRE_NEW_SECRET_KEY"
+
+var (
+	keyFile    string
+	newKeyFile string
+	legacy     bool
+)
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen FILE",
+	Short: "Write a new random secret key to FILE, readable only by you",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := secret.WriteKeyFile(args[0])
+		return err
+	},
+}
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt FILE...",
+	Short: "Seal every DEC[...] value in the files in place",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := secret.LoadKey(keyFile)
+		if err != nil {
+			return err
+		}
+		return rewrite(args, func(b []byte) ([]byte, error) {
+			return secret.EncryptText(b, key)
+		})
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt FILE...",
+	Short: "Turn every ENC[...] value in the files back into DEC[...] in place",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := secret.LoadKey(keyFile)
+		if err != nil {
+			return err
+		}
+		return rewrite(args, func(b []byte) ([]byte, error) {
+			return secret.DecryptText(b, key)
+		})
+	},
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate FILE...",
+	Short: "Re-seal every ENC[...] value in the files with a new key",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		load := secret.LoadKey
+		if legacy {
+			load = secret.LoadLegacyKey
+		}
+		old, err := load(keyFile)
+		if err != nil {
+			return err
+		}
+		var new secret.Key
+		switch {
+		case os.Getenv(newKeyEnv) != "":
+			if new, err = secret.ParseKey(os.Getenv(newKeyEnv)); err != nil {
+				return errors.New(newKeyEnv + ": " + err.Error())
+			}
+		case newKeyFile != "":
+			b, err := ioutil.ReadFile(newKeyFile)
+			if err != nil {
+				return err
+			}
+			if new, err = secret.ParseKey(string(b)); err != nil {
+				return errors.New(newKeyFile + ": " + err.Error())
+			}
+		default:
+			return errors.New("no new key: set " + newKeyEnv + " or --new-key-file")
+		}
+		return rewrite(args, func(b []byte) ([]byte, error) {
+			return secret.RotateText(b, old, new)
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{encryptCmd, decryptCmd, rotateCmd} {
+		c.Flags().StringVar(&keyFile, "key-file", "", "file holding the key, when "+secret.KeyEnv+" is not set")
+		rootCmd.AddCommand(c)
+	}
+	rotateCmd.Flags().StringVar(&newKeyFile, "new-key-file", "", "file holding the new key, when "+newKeyEnv+" is not set")
+	rotateCmd.Flags().BoolVar(&legacy, "legacy", false, "the old key is a passphrase, as used before keys were generated with keygen")
+	rootCmd.AddCommand(keygenCmd)
+}
+
+// rewrite applies fn to each file's contents, replacing the files only once
+// every one of them has been transformed successfully.
+func rewrite(files []string, fn func([]byte) ([]byte, error)) error {
+	out := make([][]byte, len(files))
+	for i, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if out[i], err = fn(b); err != nil {
+			return errors.New(f + ": " + err.Error())
+		}
+	}
+	for i, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(f, out[i], info.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/gofunct/require/gen"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	deriveDir    string
+	deriveType   string
+	deriveOutput string
+	deriveSample string
+)
+
+var deriveCmd = &cobra.Command{
+	Use:   "derive",
+	Short: "Write a requirements manifest and sample config for a Go struct",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := gen.Derive(deriveDir, deriveType)
+		if err != nil {
+			return err
+		}
+		b, err := m.Marshal()
+		if err != nil {
+			return err
+		}
+		if deriveOutput == "-" {
+			if _, err := os.Stdout.Write(b); err != nil {
+				return err
+			}
+		} else if err := ioutil.WriteFile(deriveOutput, b, 0644); err != nil {
+			return err
+		}
+		if deriveSample == "" {
+			return nil
+		}
+		vals, err := m.Sample()
+		if err != nil {
+			return err
+		}
+		b, err = yaml.Marshal(vals)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(deriveSample, b, 0644)
+	},
+}
+
+func init() {
+	deriveCmd.Flags().StringVarP(&deriveDir, "dir", "d", ".", "directory of the package declaring the struct")
+	deriveCmd.Flags().StringVarP(&deriveType, "type", "t", "Config", "name of the struct type")
+	deriveCmd.Flags().StringVarP(&deriveOutput, "output", "o", "require.manifest.yaml", "manifest file to write, or - for standard output")
+	deriveCmd.Flags().StringVar(&deriveSample, "sample", "", "also write a sample YAML config to this file")
+	rootCmd.AddCommand(deriveCmd)
+}
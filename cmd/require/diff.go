@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/gofunct/require"
+	"github.com/gofunct/require/source"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffManifest  string
+	diffOutput    string
+	diffEnv       bool
+	diffEnvPrefix string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD [NEW]",
+	Short: "Show the keys that differ between two config files",
+	Long: `Compare two config files in any supported format, such as the staging
+and production profiles, listing the keys NEW adds, removes and changes
+relative to OLD. With --env both are resolved against the environment first,
+as an Enforcer with --env-prefix would see them. Given a single file, compare
+it with what such an Enforcer sees once the environment is applied.
+
+Values of keys the requirements manifest marks secret are masked. The exit
+status is 0 when the configurations match, 1 when they differ and 2 when they
+could not be compared.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d, err := diffConfigs(args)
+		if err != nil {
+			return exitError{code: 2, err: err}
+		}
+		if err := writeDiff(d); err != nil {
+			return exitError{code: 2, err: err}
+		}
+		if !d.Empty() {
+			return exitError{code: 1}
+		}
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffManifest, "manifest", "m", "", "requirements manifest marking which keys are secret")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "text", "output format: text or json")
+	diffCmd.Flags().BoolVar(&diffEnv, "env", false, "resolve both files against the environment")
+	diffCmd.Flags().StringVar(&diffEnvPrefix, "env-prefix", "", "EnvPrefix of the Enforcer reading the files")
+	diffCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitError{code: 2, err: err}
+	})
+	rootCmd.AddCommand(diffCmd)
+}
+
+func diffConfigs(args []string) (require.Diff, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return require.Diff{}, errors.New("expected one or two config files")
+	}
+	if diffOutput != "text" && diffOutput != "json" {
+		return require.Diff{}, errors.New("unknown output format " + diffOutput)
+	}
+	secret, err := manifestSecrets(diffManifest)
+	if err != nil {
+		return require.Diff{}, err
+	}
+	vals := make([]map[string]interface{}, len(args))
+	for i, f := range args {
+		if vals[i], err = source.ReadFile(f); err != nil {
+			return require.Diff{}, err
+		}
+	}
+	var d require.Diff
+	switch {
+	case len(args) == 1:
+		if d, err = diffEnforcer(args[0]).Drift(); err != nil {
+			return require.Diff{}, err
+		}
+	case diffEnv:
+		d = diffEnforcer(args[0]).Diff(diffEnforcer(args[1]))
+	default:
+		d = require.DiffValues(vals[0], vals[1])
+	}
+	return d.Mask(secret), nil
+}
+
+// diffEnforcer returns an Enforcer reading file as a program with
+// --env-prefix would.
+func diffEnforcer(file string) *require.Enforcer {
+	return require.NewEnforcer(require.WithConfigFile(file), func(e *require.Enforcer) {
+		e.EnvPrefix = diffEnvPrefix
+	})
+}
+
+func writeDiff(d require.Diff) error {
+	if diffOutput == "json" {
+		b, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(append(b, '\n'))
+		return err
+	}
+	_, err := os.Stdout.WriteString(d.String())
+	return err
+}
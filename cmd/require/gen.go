@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gofunct/require/gen"
+	"github.com/gofunct/require/manifest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genManifest string
+	genPackage  string
+	genOutput   string
+)
+
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate typed config accessors from a requirements manifest",
+	Long: `Generate a Go file with a Config struct, a Load function and one typed
+accessor per key in a requirements manifest. Run it from a go:generate
+directive, where the package name defaults to $GOPACKAGE:
+
+	//go:generate require gen -m require.manifest.yaml -o config_gen.go`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := manifest.Load(genManifest)
+		if err != nil {
+			return err
+		}
+		pkg := genPackage
+		if pkg == "" {
+			pkg = os.Getenv("GOPACKAGE")
+		}
+		if pkg == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			pkg = filepath.Base(wd)
+		}
+		src, err := gen.Generate(m, pkg)
+		if err != nil {
+			return err
+		}
+		if genOutput == "-" {
+			_, err = os.Stdout.Write(src)
+			return err
+		}
+		return ioutil.WriteFile(genOutput, src, 0644)
+	},
+}
+
+func init() {
+	genCmd.Flags().StringVarP(&genManifest, "manifest", "m", "require.manifest.yaml", "requirements manifest to generate from")
+	genCmd.Flags().StringVarP(&genPackage, "package", "p", "", "package name of the generated file")
+	genCmd.Flags().StringVarP(&genOutput, "output", "o", "config_gen.go", "file to write, or - for standard output")
+	rootCmd.AddCommand(genCmd)
+}
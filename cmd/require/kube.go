@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/kube"
+	"github.com/gofunct/require/manifest"
+	"github.com/gofunct/require/source"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeManifest  string
+	kubeName      string
+	kubeNamespace string
+	kubeEnvPrefix string
+	kubeEnv       bool
+)
+
+var kubeCmd = &cobra.Command{
+	Use:   "kube CONFIG",
+	Short: "Render a config file as a Kubernetes ConfigMap and Secret",
+	Long: `Render the values in a config file as a ConfigMap and, for the keys a
+requirements manifest marks secret, a Secret. With --env the env and envFrom
+entries for a container spec follow the manifest.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vals, err := source.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		secret, err := manifestSecrets(kubeManifest)
+		if err != nil {
+			return err
+		}
+		leaves := map[string]interface{}{}
+		for _, k := range helm.Leaves(vals) {
+			leaves[k], _ = helm.Lookup(vals, k)
+		}
+		b, err := kube.Render(kubeName, kubeNamespace, kubeEnvPrefix, leaves, secret)
+		if err != nil {
+			return err
+		}
+		out, err := b.YAML()
+		if err != nil {
+			return err
+		}
+		if kubeEnv {
+			for _, snippet := range []func() ([]byte, error){b.EnvYAML, b.EnvFromYAML} {
+				s, err := snippet()
+				if err != nil {
+					return err
+				}
+				out = append(out, "# "+strings.Replace(strings.TrimSpace(string(s)), "\n", "\n# ", -1)+"\n"...)
+			}
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// manifestSecrets reports which keys the requirements manifest at path
+// marks secret. With no manifest no key is secret.
+func manifestSecrets(path string) (func(key string) bool, error) {
+	secrets := map[string]bool{}
+	if path != "" {
+		m, err := manifest.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range m.Requirements {
+			secrets[strings.ToLower(r.Key)] = r.Secret
+		}
+	}
+	return func(key string) bool {
+		return secrets[strings.ToLower(key)]
+	}, nil
+}
+
+func init() {
+	kubeCmd.Flags().StringVarP(&kubeManifest, "manifest", "m", "", "requirements manifest marking which keys are secret")
+	kubeCmd.Flags().StringVar(&kubeName, "name", "config", "name of the ConfigMap and Secret")
+	kubeCmd.Flags().StringVarP(&kubeNamespace, "namespace", "n", "", "namespace of the ConfigMap and Secret")
+	kubeCmd.Flags().StringVar(&kubeEnvPrefix, "env-prefix", "", "EnvPrefix of the Enforcer reading the values")
+	kubeCmd.Flags().BoolVar(&kubeEnv, "env", false, "append the env and envFrom entries as comments")
+	rootCmd.AddCommand(kubeCmd)
+}
@@ -0,0 +1,45 @@
+// Command require manages the config files used by require Enforcers.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var rootCmd = &cobra.Command{
+	Use:           "require",
+	Short:         "Manage config files used by require",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+// exitError makes a command exit with code rather than 1, printing err
+// first when it is set.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	if e.err == nil {
+		return "exit status " + strconv.Itoa(e.code)
+	}
+	return e.err.Error()
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		code := 1
+		if e, ok := err.(exitError); ok {
+			code = e.code
+			if e.err == nil {
+				os.Exit(code)
+			}
+		}
+		fmt.Fprintln(os.Stderr, "require:", err)
+		os.Exit(code)
+	}
+}
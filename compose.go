@@ -0,0 +1,36 @@
+package require
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/source"
+)
+
+// ComposeSource returns a source reading the environment service receives
+// in a docker-compose file, matching variables to the Enforcer's
+// requirements through its EnvPrefix. Adding it to Sources checks the
+// requirements against what the container will see.
+func (e *Enforcer) ComposeSource(file, service string) *source.Compose {
+	c := source.NewCompose(file, service)
+	c.Prefix = e.EnvPrefix
+	c.Keys = e.requirements()
+	return c
+}
+
+// ExportCompose writes the current value of every requirement into the
+// environment block of service in a docker-compose file. Secret values
+// are left out: they belong in an env_file kept out of version control.
+func (e *Enforcer) ExportCompose(ctx context.Context, file, service string) error {
+	vals := map[string]interface{}{}
+	e.mu.Lock()
+	for _, key := range e.Requirements {
+		if e.secret[strings.ToLower(key)] || !e.v.IsSet(key) {
+			continue
+		}
+		helm.SetPath(vals, strings.ToLower(key), e.v.Get(key))
+	}
+	e.mu.Unlock()
+	return e.ComposeSource(file, service).Write(ctx, vals)
+}
@@ -0,0 +1,130 @@
+package decider
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofunct/require/internal/csvlist"
+	"github.com/tcnksm/go-input"
+	"go.uber.org/zap"
+)
+
+// ErrTimeout is returned by a prompt that was not answered within the
+// Decider's Timeout when FailOnTimeout is set or there is no default.
+var ErrTimeout = errors.New("query error: timed out waiting for input")
+
+// AskStringContext is AskString that returns ctx.Err() if ctx is done, or
+// the process is interrupted, before the question is answered.
+func (d *Decider) AskStringContext(ctx context.Context, q string, def string, required bool) (string, error) {
+	return d.askContext(ctx, q, &input.Options{
+		Default:      def,
+		Loop:         required,
+		Required:     required,
+		ValidateFunc: Ensure(required),
+	})
+}
+
+// AskIntContext is AskInt that returns ctx.Err() if ctx is done before the
+// question is answered.
+func (d *Decider) AskIntContext(ctx context.Context, q string, def string, required bool) (int, error) {
+	ans, err := d.askContext(ctx, q, &input.Options{
+		Default:      def,
+		Loop:         required,
+		Required:     required,
+		ValidateFunc: Ensure(required),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(ans)
+}
+
+// AskStringSliceContext is AskStringSlice that returns ctx.Err() if ctx is
+// done before the question is answered.
+func (d *Decider) AskStringSliceContext(ctx context.Context, q string, def string, required bool) ([]string, error) {
+	ans, err := d.askContext(ctx, q, &input.Options{
+		Default:      def,
+		Loop:         required,
+		Required:     required,
+		ValidateFunc: EnsureSlice(required),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return csvlist.Parse(ans)
+}
+
+// AskStringMapStringContext is AskStringMapString that returns ctx.Err() if
+// ctx is done before the question is answered.
+func (d *Decider) AskStringMapStringContext(ctx context.Context, q string, def string, required bool) (map[string]string, error) {
+	ans, err := d.askContext(ctx, q, &input.Options{
+		Default:      def,
+		Loop:         required,
+		Required:     required,
+		ValidateFunc: EnsureSlice(required),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return csvlist.ParseMap(ans)
+}
+
+// askContext asks q and waits for an answer, ctx to be done or the prompt
+// timeout to pass, whichever comes first. A prompt that times out answers
+// with its default unless FailOnTimeout is set.
+func (d *Decider) askContext(ctx context.Context, q string, opts *input.Options) (string, error) {
+	terminal.Lock()
+	defer terminal.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	pctx := ctx
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		pctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	type result struct {
+		ans string
+		err error
+	}
+	log := d.log().With(zap.String("question", q))
+	log.Debug("prompt shown", zap.Bool("has_default", opts.Default != ""), zap.Duration("timeout", d.Timeout))
+	done := make(chan result, 1)
+	go func() {
+		ans, err := d.ask.Ask(q, opts)
+		done <- result{ans, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err == input.ErrInterrupted {
+			// go-input catches SIGINT itself and leaves its read pending.
+			d.in.cancel()
+			log.Info("prompt interrupted")
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			return "", context.Canceled
+		}
+		if r.err != nil {
+			log.Warn("prompt failed", zap.Error(r.err))
+		} else {
+			log.Debug("prompt answered")
+		}
+		return r.ans, r.err
+	case <-pctx.Done():
+		d.in.cancel()
+		<-done
+		if err := ctx.Err(); err != nil {
+			log.Info("prompt cancelled", zap.Error(err))
+			return "", err
+		}
+		if d.FailOnTimeout || opts.Default == "" {
+			log.Warn("prompt timed out")
+			return "", ErrTimeout
+		}
+		log.Warn("prompt timed out, using default")
+		return opts.Default, nil
+	}
+}
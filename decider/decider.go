@@ -1,33 +1,58 @@
 package decider
 
 import (
+	"context"
 	"fmt"
-	"github.com/gofunct/gofs"
 	"github.com/tcnksm/go-input"
+	"go.uber.org/zap"
 	"gopkg.in/dixonwille/wmenu.v4"
-	"strconv"
+	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
+// terminal serializes prompts so that Deciders used from several
+// goroutines never ask questions on the terminal at the same time.
+var terminal sync.Mutex
+
+// stdin is shared by every Decider: its goroutine owns os.Stdin for the
+// life of the process, so a reader per Decider would leave goroutines
+// competing for, and swallowing, each other's input.
+var stdin = newCancelReader(os.Stdin)
+
 type Decider struct {
 	ask  *input.UI
 	menu *wmenu.Menu
+	in   *cancelReader
+	// Timeout bounds how long a single prompt waits for an answer. Zero
+	// waits until the context is done.
+	Timeout time.Duration
+	// FailOnTimeout makes a prompt that times out return ErrTimeout rather
+	// than its default value.
+	FailOnTimeout bool
+	// Logger receives an event for every prompt. Answers to string prompts
+	// are never logged since they may be secrets.
+	Logger *zap.Logger
 }
 
 func NewDecider(q string) *Decider {
 	return &Decider{
-		ask: input.DefaultUI(),
+		ask:  &input.UI{Writer: os.Stdout, Reader: stdin},
 		menu: wmenu.NewMenu(q),
+		in:   stdin,
+	}
+}
+
+func (d *Decider) log() *zap.Logger {
+	if d.Logger == nil {
+		return zap.NewNop()
 	}
+	return d.Logger
 }
 
 func (d *Decider) AskString(q string, def string, required bool) string {
-	ans, err := d.ask.Ask(q, &input.Options{
-		Default:      def,
-		Loop:         required,
-		Required:     required,
-		ValidateFunc: Ensure(required),
-	})
+	ans, err := d.AskStringContext(context.Background(), q, def, required)
 	if err != nil {
 		panic(err)
 	}
@@ -35,59 +60,32 @@ func (d *Decider) AskString(q string, def string, required bool) string {
 }
 
 func (d *Decider) AskInt(q string, def string, required bool) int {
-	ans, err := d.ask.Ask(q, &input.Options{
-		Default:      def,
-		Loop:         required,
-		Required:     required,
-		ValidateFunc: Ensure(required),
-	})
-	if err != nil {
-		panic(err)
-	}
-	intans, err := strconv.Atoi(ans)
+	ans, err := d.AskIntContext(context.Background(), q, def, required)
 	if err != nil {
 		panic(err)
 	}
-	return intans
+	return ans
 }
 
 func (d *Decider) AskStringSlice(q string, def string, required bool) []string {
-	ans, err := d.ask.Ask(q, &input.Options{
-		Default:      def,
-		Loop:         required,
-		Required:     required,
-		ValidateFunc: EnsureSlice(required),
-	})
+	ans, err := d.AskStringSliceContext(context.Background(), q, def, required)
 	if err != nil {
 		panic(err)
 	}
-	sliceans, err := gofs.ReadAsCSV(ans)
-	if err != nil {
-		panic(err)
-	}
-
-	return sliceans
+	return ans
 }
 
 func (d *Decider) AskStringMapString(q string, def string, required bool) map[string]string {
-	ans, err := d.ask.Ask(q, &input.Options{
-		Default:      def,
-		Loop:         required,
-		Required:     required,
-		ValidateFunc: EnsureSlice(required),
-	})
+	ans, err := d.AskStringMapStringContext(context.Background(), q, def, required)
 	if err != nil {
 		panic(err)
 	}
-	newMap, err := gofs.ReadAsMap(ans)
-	if err != nil {
-		panic(err)
-	}
-	return newMap
+	return ans
 }
 
-
 func (d *Decider) AskYn(q string, def int) bool {
+	terminal.Lock()
+	defer terminal.Unlock()
 	q = "y/n | "+q
 	var ans bool
 	var errrr error
@@ -98,7 +96,7 @@ func (d *Decider) AskYn(q string, def int) bool {
 		} else {
 			ans = false
 		}
-		fmt.Printf("registered response of %s.\n", opts[0].Value.(string))
+		d.log().Debug("registered response", zap.String("question", q), zap.Bool("answer", ans))
 		return nil
 	}
 	d.menu.Action(actFunc)
@@ -112,6 +110,8 @@ func (d *Decider) AskYn(q string, def int) bool {
 
 
 func (d *Decider) AskTF(q string, def string) bool {
+	terminal.Lock()
+	defer terminal.Unlock()
 	var ans bool
 	var errrr error
 	q = "t/f | "+q
@@ -130,7 +130,7 @@ func (d *Decider) AskTF(q string, def string) bool {
 		} else {
 			ans = false
 		}
-		fmt.Printf("registered response of %s.\n", opts[0].Value.(string))
+		d.log().Debug("registered response", zap.String("question", q), zap.Bool("answer", ans))
 		return nil
 	}
 	d.menu.Action(actFunc)
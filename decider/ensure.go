@@ -2,7 +2,8 @@ package decider
 
 import (
 	"errors"
-	"github.com/gofunct/gofs"
+	"github.com/gofunct/require/internal/csvlist"
+	"strconv"
 	"strings"
 )
 
@@ -26,7 +27,7 @@ func EnsureSlice(required bool) func(s string) error {
 		if len(s) > 50 {
 			return errors.New("query error: over 50 characters")
 		}
-		slice, err := gofs.ReadAsCSV(s)
+		slice, err := csvlist.Parse(s)
 		if err != nil {
 			return err
 		}
@@ -46,12 +47,12 @@ func EnsureSliceLength(required bool, length int) func(s string) error {
 		if len(s) > 50 {
 			return errors.New("query error: over 50 characters")
 		}
-		slice, err := gofs.ReadAsCSV(s)
+		slice, err := csvlist.Parse(s)
 		if err != nil {
 			return err
 		}
 		if len(slice) != length {
-			return errors.New("expected a slice of length: " + string(length) + "\n" + "got length: " + string(len(slice)))
+			return errors.New("expected a slice of length: " + strconv.Itoa(length) + "\n" + "got length: " + strconv.Itoa(len(slice)))
 		}
 
 		return nil
@@ -67,7 +68,7 @@ func EnsureSliceMap(required bool) func(s string) error {
 		if len(s) > 50 {
 			return errors.New("query error: over 50 characters")
 		}
-		slice, err := gofs.ReadAsCSV(s)
+		slice, err := csvlist.Parse(s)
 		if err != nil {
 			return err
 		}
@@ -92,7 +93,7 @@ func EnsureSliceMap(required bool) func(s string) error {
 			vals = append(vals, v)
 		}
 		if len(keys) != len(vals) {
-			return errors.New("mismatched key and value arrays\n" + "keylength: " + string(len(keys)) + " val length: " + string(len(vals)))
+			return errors.New("mismatched key and value arrays\n" + "keylength: " + strconv.Itoa(len(keys)) + " val length: " + strconv.Itoa(len(vals)))
 		}
 
 		return nil
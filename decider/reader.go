@@ -0,0 +1,81 @@
+package decider
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var errCanceled = errors.New("read canceled")
+
+// cancelReader reads from src on a single background goroutine so a
+// pending Read can be abandoned without losing input: whatever arrives
+// after a cancel is handed to the next prompt.
+type cancelReader struct {
+	src     io.Reader
+	once    sync.Once
+	chunks  chan []byte
+	err     error
+	mu      sync.Mutex
+	pending []byte
+	stop    chan struct{}
+}
+
+func newCancelReader(src io.Reader) *cancelReader {
+	return &cancelReader{src: src, stop: make(chan struct{})}
+}
+
+func (r *cancelReader) start() {
+	r.chunks = make(chan []byte)
+	go func() {
+		defer close(r.chunks)
+		for {
+			buf := make([]byte, 4096)
+			n, err := r.src.Read(buf)
+			if n > 0 {
+				r.chunks <- buf[:n]
+			}
+			if err != nil {
+				r.err = err
+				return
+			}
+		}
+	}()
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	r.mu.Lock()
+	if len(r.pending) > 0 {
+		n := copy(p, r.pending)
+		r.pending = r.pending[n:]
+		r.mu.Unlock()
+		return n, nil
+	}
+	stop := r.stop
+	r.mu.Unlock()
+	select {
+	case b, ok := <-r.chunks:
+		if !ok {
+			if r.err != nil {
+				return 0, r.err
+			}
+			return 0, io.EOF
+		}
+		n := copy(p, b)
+		r.mu.Lock()
+		r.pending = append(r.pending, b[n:]...)
+		r.mu.Unlock()
+		return n, nil
+	case <-stop:
+		return 0, errCanceled
+	}
+}
+
+// cancel makes any Read in progress return errCanceled.
+func (r *cancelReader) cancel() {
+	r.mu.Lock()
+	close(r.stop)
+	r.stop = make(chan struct{})
+	r.mu.Unlock()
+}
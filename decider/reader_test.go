@@ -0,0 +1,63 @@
+package decider
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestDecidersShareStdin(t *testing.T) {
+	a, b := NewDecider("a"), NewDecider("b")
+	if a.in != b.in {
+		t.Error("each Decider reads stdin through its own reader")
+	}
+}
+
+func TestCancelledReadKeepsInput(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r := newCancelReader(pr)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 16))
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	r.cancel()
+	if err := <-errs; err != errCanceled {
+		t.Fatalf("Read after cancel = %v, want errCanceled", err)
+	}
+	go pw.Write([]byte("answer\n"))
+	buf := make([]byte, 3)
+	var got []byte
+	for len(got) < 7 {
+		n, err := r.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if string(got) != "answer\n" {
+		t.Errorf("read %q after a cancel, want %q", got, "answer\n")
+	}
+}
+
+func TestAskContextTimesOutWithDefault(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	d := NewDecider("q")
+	d.in = newCancelReader(pr)
+	d.ask.Reader = d.in
+	d.ask.Writer = ioutil.Discard
+	d.Timeout = 20 * time.Millisecond
+	ans, err := d.AskStringContext(context.Background(), "name?", "dflt", false)
+	if err != nil || ans != "dflt" {
+		t.Errorf("AskStringContext = %q, %v; want the default", ans, err)
+	}
+	d.FailOnTimeout = true
+	if _, err := d.AskStringContext(context.Background(), "name?", "dflt", false); err != ErrTimeout {
+		t.Errorf("AskStringContext with FailOnTimeout = %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,78 @@
+package require
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RequireCommand requires key and, when it has to be asked for, offers the
+// output of the bash command cmd as the default answer, for example
+// "git rev-parse --short HEAD" or "gcloud config get-value project". The
+// command only runs when key is missing, and each command runs at most once
+// per Init however many requirements share it.
+func (e *Enforcer) RequireCommand(key, cmd string) {
+	e.prompt.Lock()
+	if e.commands == nil {
+		e.commands = map[string]string{}
+	}
+	e.commands[strings.ToLower(key)] = cmd
+	e.prompt.Unlock()
+	e.addRequirements(key)
+}
+
+// commandDefault returns the default for key computed by its command, or ""
+// if it has none or the command fails. e.prompt must be held.
+func (e *Enforcer) commandDefault(ctx context.Context, key string) string {
+	cmd, ok := e.commands[strings.ToLower(key)]
+	if !ok {
+		return ""
+	}
+	if val, ok := e.computed[cmd]; ok {
+		return val
+	}
+	e.log().Debug("computing default", zap.String("key", key), zap.String("command", cmd))
+	val, err := runCommand(ctx, cmd, e.CommandTimeout)
+	if err != nil {
+		e.log().Warn("default could not be computed", zap.String("key", key), zap.Error(err))
+		val = ""
+	}
+	if e.computed == nil {
+		e.computed = map[string]string{}
+	}
+	e.computed[cmd] = val
+	return val
+}
+
+// runCommand runs cmd through bash and returns its trimmed standard output.
+// A command still running when timeout passes or ctx is done is killed.
+func runCommand(ctx context.Context, cmd string, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var out, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "bash", "-c", cmd)
+	c.Stdout = &out
+	c.Stderr = &stderr
+	// Stop waiting for output held open by anything the command started
+	// in the background once it has been killed.
+	c.WaitDelay = time.Second
+	if err := c.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New("command " + cmd + " failed: " + msg)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,56 @@
+package require
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	out, err := runCommand(context.Background(), "echo '  hello  '", time.Second)
+	if err != nil || out != "hello" {
+		t.Errorf("runCommand = %q, %v; want hello", out, err)
+	}
+	if _, err := runCommand(context.Background(), "echo oops >&2; exit 1", time.Second); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("runCommand of a failing command = %v, want its stderr", err)
+	}
+}
+
+func TestRunCommandKilledOnTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := runCommand(context.Background(), "sleep 30", 100*time.Millisecond)
+	if err != context.DeadlineExceeded {
+		t.Errorf("runCommand = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("runCommand returned after %v, the command was not killed", d)
+	}
+}
+
+func TestRequirementsAddedDuringReload(t *testing.T) {
+	file := filepath.Join(writeFiles(t, map[string]string{"app.yaml": "a: 1\n"}), "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	doc := []byte("a: 1\n")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			e.RequireSecret("s")
+			e.RequireCommand("c", "true")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			e.reload(doc, doc)
+		}
+	}()
+	wg.Wait()
+	if n := len(e.requirements()); n != 100 {
+		t.Errorf("%d requirements, want 100", n)
+	}
+}
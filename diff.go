@@ -0,0 +1,177 @@
+package require
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/source"
+	"github.com/spf13/viper"
+)
+
+// masked replaces the values of secret keys wherever they are reported.
+const masked = "******"
+
+// Change is a single key whose value differs between two configurations.
+// Old is nil for added keys and New is nil for removed keys.
+type Change struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// Diff lists the keys that were added, removed or changed between two
+// configurations, each sorted by key.
+type Diff struct {
+	Added   []Change `json:"added,omitempty"`
+	Removed []Change `json:"removed,omitempty"`
+	Changed []Change `json:"changed,omitempty"`
+	// Err is set when a reload was rejected. The previous config stays in
+	// effect and the change lists are empty.
+	Err error `json:"-"`
+}
+
+// Diff compares the resolved settings of e with those of other, listing
+// the keys other adds, removes and changes. Values of keys that either
+// Enforcer holds secret are masked.
+func (e *Enforcer) Diff(other *Enforcer) Diff {
+	d := diffSettings(e.snapshot(), other.snapshot())
+	return d.Mask(func(key string) bool {
+		return e.IsSecret(key) || other.IsSecret(key)
+	})
+}
+
+// Drift compares the config file as it was last written with the current
+// settings, which also hold values from the environment, flags, prompts,
+// sources and Set. Added lists the keys that are live but were never
+// persisted, Changed the keys whose live value differs from the file's.
+// Secret values are masked.
+func (e *Enforcer) Drift() (Diff, error) {
+	e.mu.Lock()
+	file := e.v.ConfigFileUsed()
+	e.mu.Unlock()
+	persisted := map[string]interface{}{}
+	if file != "" {
+		vals, err := source.ReadFile(file)
+		if err != nil {
+			return Diff{}, err
+		}
+		persisted = vals
+	}
+	d := DiffValues(persisted, e.snapshot())
+	return d.Mask(e.IsSecret), nil
+}
+
+// DiffValues compares two sets of config values, such as two files read
+// with source.ReadFile, key by key through nested maps.
+func DiffValues(old, new map[string]interface{}) Diff {
+	return diffSettings(flatten(old), flatten(new))
+}
+
+// Mask returns a copy of d with the values of every key secret reports
+// replaced by a mask.
+func (d Diff) Mask(secret func(key string) bool) Diff {
+	mask := func(l []Change) []Change {
+		out := make([]Change, len(l))
+		for i, c := range l {
+			if secret(c.Key) {
+				if c.Old != nil {
+					c.Old = masked
+				}
+				if c.New != nil {
+					c.New = masked
+				}
+			}
+			out[i] = c
+		}
+		return out
+	}
+	return Diff{Added: mask(d.Added), Removed: mask(d.Removed), Changed: mask(d.Changed), Err: d.Err}
+}
+
+// String lists the changes one per line: added keys prefixed with +,
+// removed keys with - and changed keys with ~.
+func (d Diff) String() string {
+	var b strings.Builder
+	for _, c := range d.Added {
+		fmt.Fprintf(&b, "+ %s: %v\n", c.Key, c.New)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(&b, "- %s: %v\n", c.Key, c.Old)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s: %v -> %v\n", c.Key, c.Old, c.New)
+	}
+	return b.String()
+}
+
+// Empty reports whether the diff has no changes.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// All returns every change in the diff sorted by key.
+func (d Diff) All() []Change {
+	all := make([]Change, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	all = append(all, d.Added...)
+	all = append(all, d.Removed...)
+	all = append(all, d.Changed...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Key < all[j].Key })
+	return all
+}
+
+// settings flattens the resolved values of v into a map of dotted keys.
+func settings(v *viper.Viper) map[string]interface{} {
+	keys := v.AllKeys()
+	m := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		m[k] = v.Get(k)
+	}
+	return m
+}
+
+// flatten returns the leaves of vals keyed by their dotted paths, as
+// settings does for a viper instance.
+func flatten(vals map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := strings.ToLower(k)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				walk(key, sub)
+				continue
+			}
+			out[key] = v
+		}
+	}
+	walk("", helm.Normalize(vals).(map[string]interface{}))
+	return out
+}
+
+func diffSettings(old, new map[string]interface{}) Diff {
+	var d Diff
+	for k, nv := range new {
+		ov, ok := old[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, Change{Key: k, New: nv})
+		case !reflect.DeepEqual(ov, nv):
+			d.Changed = append(d.Changed, Change{Key: k, Old: ov, New: nv})
+		}
+	}
+	for k, ov := range old {
+		if _, ok := new[k]; !ok {
+			d.Removed = append(d.Removed, Change{Key: k, Old: ov})
+		}
+	}
+	for _, l := range [][]Change{d.Added, d.Removed, d.Changed} {
+		sort.Slice(l, func(i, j int) bool { return l[i].Key < l[j].Key })
+	}
+	return d
+}
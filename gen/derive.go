@@ -0,0 +1,217 @@
+package gen
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/gofunct/require/manifest"
+)
+
+// Derive builds a requirements manifest from the struct type typeName
+// declared in the package in dir. Each field becomes a requirement keyed by
+// its require, mapstructure, yaml or json tag, in that order of preference,
+// and documented by its doc comment. Nested structs contribute their fields
+// under the parent's key, and embedded or squashed ones at the parent's
+// level. The require tag also takes options after the key:
+//
+//	Host string `require:"db.host,required,env=DB_HOST"`
+//	Pass string `require:",secret"`
+//	Wait time.Duration `require:",default=5s"`
+func Derive(dir, typeName string) (*manifest.Manifest, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	var name string
+	for n, p := range pkgs {
+		for _, f := range p.Files {
+			if f.Scope.Lookup(typeName) != nil {
+				name = n
+			}
+		}
+	}
+	if name == "" {
+		return nil, errors.New("no type " + typeName + " in " + dir)
+	}
+	for _, f := range pkgs[name].Files {
+		files = append(files, f)
+	}
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// Types from packages that cannot be loaded are reported as
+		// invalid below rather than failing the whole package.
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(name, fset, files, info)
+	obj := pkg.Scope().Lookup(typeName)
+	if obj == nil {
+		return nil, errors.New("no type " + typeName + " in " + dir)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, errors.New(typeName + " is not a struct")
+	}
+	d := &deriver{docs: fieldDocs(files, info), m: &manifest.Manifest{}}
+	if err := d.walk(st, ""); err != nil {
+		return nil, err
+	}
+	return d.m, d.m.Validate()
+}
+
+type deriver struct {
+	docs map[types.Object]string
+	m    *manifest.Manifest
+}
+
+func (d *deriver) walk(st *types.Struct, prefix string) error {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag := parseTag(reflect.StructTag(st.Tag(i)))
+		if tag.skip || (!f.Exported() && !f.Anonymous()) {
+			continue
+		}
+		key := tag.key
+		if key == "" {
+			key = strings.ToLower(f.Name())
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		typ := f.Type()
+		if p, ok := typ.(*types.Pointer); ok {
+			typ = p.Elem()
+		}
+		if s, ok := typ.Underlying().(*types.Struct); ok && typ.String() != "time.Time" {
+			next := key
+			if (f.Anonymous() && tag.key == "") || tag.squash {
+				next = prefix
+			}
+			if err := d.walk(s, next); err != nil {
+				return err
+			}
+			continue
+		}
+		kind, err := manifestType(typ)
+		if err != nil {
+			return errors.New("field " + f.Name() + ": " + err.Error())
+		}
+		d.m.Requirements = append(d.m.Requirements, manifest.Requirement{
+			Key:      key,
+			Type:     kind,
+			Usage:    d.docs[f],
+			Default:  tag.def,
+			Env:      tag.env,
+			Secret:   tag.secret,
+			Required: tag.required,
+		})
+	}
+	return nil
+}
+
+// manifestType maps a Go type to the manifest type it is read as.
+func manifestType(t types.Type) (string, error) {
+	switch t.String() {
+	case "time.Duration":
+		return "duration", nil
+	case "time.Time":
+		return "string", nil
+	case "[]string":
+		return "[]string", nil
+	case "map[string]string":
+		return "map[string]string", nil
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return "", errors.New("unsupported type " + t.String())
+	}
+	switch {
+	case b.Kind() == types.Int64:
+		return "int64", nil
+	case b.Info()&types.IsInteger != 0:
+		return "int", nil
+	case b.Info()&types.IsFloat != 0:
+		return "float64", nil
+	case b.Info()&types.IsBoolean != 0:
+		return "bool", nil
+	case b.Info()&types.IsString != 0:
+		return "string", nil
+	}
+	return "", errors.New("unsupported type " + t.String())
+}
+
+type tag struct {
+	key, def, env    string
+	skip, squash     bool
+	required, secret bool
+}
+
+func parseTag(st reflect.StructTag) tag {
+	var t tag
+	for _, name := range []string{"require", "mapstructure", "yaml", "json"} {
+		v, ok := st.Lookup(name)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(v, ",")
+		if parts[0] == "-" {
+			t.skip = true
+			return t
+		}
+		if t.key == "" {
+			t.key = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "squash" || opt == "inline":
+				t.squash = true
+			case name != "require":
+			case opt == "required":
+				t.required = true
+			case opt == "secret":
+				t.secret = true
+			case strings.HasPrefix(opt, "default="):
+				t.def = strings.TrimPrefix(opt, "default=")
+			case strings.HasPrefix(opt, "env="):
+				t.env = strings.TrimPrefix(opt, "env=")
+			}
+		}
+	}
+	return t
+}
+
+// fieldDocs collects the doc or line comment of every struct field.
+func fieldDocs(files []*ast.File, info *types.Info) map[types.Object]string {
+	docs := map[types.Object]string{}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			field, ok := n.(*ast.Field)
+			if !ok {
+				return true
+			}
+			doc := field.Doc.Text()
+			if doc == "" {
+				doc = field.Comment.Text()
+			}
+			doc = strings.Join(strings.Fields(doc), " ")
+			for _, name := range field.Names {
+				if obj := info.Defs[name]; obj != nil && doc != "" {
+					docs[obj] = doc
+				}
+			}
+			return true
+		})
+	}
+	return docs
+}
@@ -0,0 +1,246 @@
+// Package gen generates a Go package of typed config accessors from a
+// requirements manifest, so programs read config through methods such as
+// cfg.DBHost() rather than GetString("db.host"). It is run by
+// "require gen", typically from a go:generate directive:
+//
+//	//go:generate require gen -m require.manifest.yaml -p config -o config_gen.go
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gofunct/require/manifest"
+)
+
+// Generate renders the Go source of package pkg for m.
+func Generate(m *manifest.Manifest, pkg string) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	fields := make([]field, len(m.Requirements))
+	names := map[string]string{}
+	for i, r := range m.Requirements {
+		f := field{Requirement: r, Name: GoName(r.Key)}
+		if prev, ok := names[f.Name]; ok {
+			return nil, errors.New("keys " + prev + " and " + r.Key + " would both be named " + f.Name)
+		}
+		names[f.Name] = r.Key
+		f.Var = unexport(f.Name)
+		f.Cast = casts[r.Type]
+		fields[i] = f
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Package string
+		Fields  []field
+	}{pkg, fields}); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.New("generated invalid Go: " + err.Error())
+	}
+	return src, nil
+}
+
+type field struct {
+	manifest.Requirement
+	Name string
+	Var  string
+	Cast string
+}
+
+// Doc returns the accessor's doc comment lines.
+func (f field) Doc() []string {
+	doc := f.Name + " returns " + f.Key
+	if f.Usage != "" {
+		doc += ", " + strings.TrimSuffix(f.Usage, ".")
+	}
+	doc += "."
+	if f.Default != "" {
+		doc += " It defaults to " + f.Default + "."
+	}
+	return wrap(doc, 74)
+}
+
+var casts = map[string]string{
+	"string":            "cast.ToStringE",
+	"bool":              "cast.ToBoolE",
+	"int":               "cast.ToIntE",
+	"int64":             "cast.ToInt64E",
+	"float64":           "cast.ToFloat64E",
+	"duration":          "cast.ToDurationE",
+	"[]string":          "cast.ToStringSliceE",
+	"map[string]string": "cast.ToStringMapStringE",
+}
+
+var goTypes = map[string]string{
+	"duration": "time.Duration",
+}
+
+var initialisms = map[string]bool{
+	"api": true, "dns": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true,
+	"udp": true, "uri": true, "url": true, "uuid": true, "db": true,
+}
+
+// GoName turns a config key such as "db.max_idle-conns" into an exported Go
+// identifier such as DBMaxIdleConns.
+func GoName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "Key" + name
+	}
+	return name
+}
+
+// unexport lowercases the leading capitals of name, keeping the last one
+// of an initialism that runs into the next word: DBHost becomes dbHost.
+// Names that would be Go keywords, such as type, get a trailing underscore.
+func unexport(name string) string {
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	if s := string(r); token.Lookup(s).IsKeyword() {
+		return s + "_"
+	}
+	return string(r)
+}
+
+func wrap(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(w) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	return append(lines, line)
+}
+
+func goType(typ string) string {
+	if t, ok := goTypes[typ]; ok {
+		return t
+	}
+	return typ
+}
+
+var tmpl = template.Must(template.New("config").Funcs(template.FuncMap{
+	"goType": goType,
+	"quote":  strconv.Quote,
+	"hasDuration": func(fields []field) bool {
+		for _, f := range fields {
+			if f.Type == "duration" {
+				return true
+			}
+		}
+		return false
+	},
+	"hasRequired": func(fields []field) bool {
+		for _, f := range fields {
+			if f.Required || f.Secret {
+				return true
+			}
+		}
+		return false
+	},
+}).Parse(`// Code generated by require gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+{{- if hasDuration .Fields}}
+	"time"
+{{- end}}
+
+	"github.com/gofunct/require"
+	"github.com/spf13/cast"
+)
+
+// Keys of the values in Config.
+const (
+{{- range .Fields}}
+	Key{{.Name}} = {{quote .Key}}
+{{- end}}
+)
+
+// Config holds the values read by Load.
+type Config struct {
+{{- range .Fields}}
+	{{.Var}} {{goType .Type}}
+{{- end}}
+}
+
+// Load registers the manifest's requirements, defaults and environment
+// variables with e, enforces them and reads every value into a Config.
+func Load(e *require.Enforcer) (*Config, error) {
+{{- range .Fields}}
+{{- if .Env}}
+	if err := e.BindEnv(Key{{.Name}}, {{quote .Env}}); err != nil {
+		return nil, err
+	}
+{{- end}}
+{{- if .Default}}
+	e.RequireDef(Key{{.Name}}, {{quote .Default}})
+{{- end}}
+{{- if .Secret}}
+	e.RequireSecret(Key{{.Name}})
+{{- else if .Required}}
+	e.Requirements = append(e.Requirements, Key{{.Name}})
+{{- end}}
+{{- end}}
+{{- if hasRequired .Fields}}
+	if err := e.Init(); err != nil {
+		return nil, err
+	}
+{{- end}}
+	c := &Config{}
+	var err error
+{{- range .Fields}}
+	if v := e.Get(Key{{.Name}}); v != nil {
+		if c.{{.Var}}, err = {{.Cast}}(v); err != nil {
+			return nil, errors.New("invalid value for " + Key{{.Name}} + ": " + err.Error())
+		}
+	}
+{{- end}}
+	return c, nil
+}
+{{range .Fields}}
+{{- range .Doc}}
+// {{.}}
+{{- end}}
+func (c *Config) {{.Name}}() {{goType .Type}} {
+	return c.{{.Var}}
+}
+{{end}}`))
@@ -0,0 +1,101 @@
+package gen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofunct/require/manifest"
+)
+
+func TestGenerateEscapesKeywords(t *testing.T) {
+	m := &manifest.Manifest{Requirements: []manifest.Requirement{
+		{Key: "type", Type: "string", Required: true},
+		{Key: "default", Type: "bool"},
+		{Key: "range", Type: "int"},
+	}}
+	src, err := Generate(m, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"return c.type_", "return c.default_", "return c.range_", "func (c *Config) Type() string"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code lacks %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateInitsOnlyForRequirements(t *testing.T) {
+	defaults := &manifest.Manifest{Requirements: []manifest.Requirement{
+		{Key: "db.timeout", Type: "duration", Default: "5s"},
+	}}
+	src, err := Generate(defaults, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "e.Init()") {
+		t.Errorf("a manifest of defaults calls Init, which fails without requirements:\n%s", src)
+	}
+	if !strings.Contains(string(src), `e.RequireDef(KeyDBTimeout, "5s")`) {
+		t.Errorf("default not registered:\n%s", src)
+	}
+	required := &manifest.Manifest{Requirements: []manifest.Requirement{
+		{Key: "db.host", Type: "string", Required: true},
+	}}
+	if src, err = Generate(required, "config"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "e.Init()") {
+		t.Errorf("required keys are not enforced:\n%s", src)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for key, want := range map[string]string{
+		"db.host":     "DBHost",
+		"api_url":     "APIURL",
+		"max-retries": "MaxRetries",
+		"2fa":         "Key2fa",
+	} {
+		if got := GoName(key); got != want {
+			t.Errorf("GoName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := `package app
+
+import "time"
+
+type Config struct {
+	// Host is the database host.
+	Host string        ` + "`require:\"db.host,required,env=DB_HOST\"`" + `
+	Wait time.Duration ` + "`require:\",default=5s\"`" + `
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Derive(dir, "Config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Requirements) != 2 {
+		t.Fatalf("Derive = %+v, want 2 requirements", m.Requirements)
+	}
+	host := m.Requirements[0]
+	if host.Key != "db.host" || !host.Required || host.Env != "DB_HOST" || host.Type != "string" {
+		t.Errorf("host = %+v", host)
+	}
+	if wait := m.Requirements[1]; wait.Default != "5s" || wait.Type != "duration" {
+		t.Errorf("wait = %+v", wait)
+	}
+}
@@ -1,15 +1,23 @@
 module github.com/gofunct/require
 
 require (
+	github.com/aws/aws-sdk-go v1.16.12
 	github.com/codegangsta/cli v1.20.0
 	github.com/daviddengcn/go-colortext v0.0.0-20180409174941-186a3d44e920 // indirect
 	github.com/dixonwille/wmenu v4.0.2+incompatible // indirect
-	github.com/gofunct/gofs v0.0.0-20190201225821-ff30dd2f57cc
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/hashicorp/go-getter v1.0.1
+	github.com/hashicorp/go-version v1.1.0
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/mgutz/str v1.2.0
+	github.com/spf13/afero v1.2.0
+	github.com/spf13/cast v1.3.0
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.3.1
 	github.com/tcnksm/go-input v0.0.0-20180404061846-548a7d7a8ee8
+	go.uber.org/zap v1.9.1
 	gopkg.in/dixonwille/wlog.v2 v2.0.0 // indirect
 	gopkg.in/dixonwille/wmenu.v4 v4.0.2
+	gopkg.in/yaml.v2 v2.2.2
 )
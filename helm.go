@@ -0,0 +1,264 @@
+package require
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/internal/csvlist"
+)
+
+// Chart returns the Helm chart found by NewHelmEnforcer, or nil if the
+// Enforcer is not backed by a chart.
+func (e *Enforcer) Chart() *helm.Chart {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.chart
+}
+
+// enforceSchema validates the resolved settings against values.schema.json,
+// prompting for any required value that is missing before reporting the
+// violations that remain.
+func (e *Enforcer) enforceSchema(ctx context.Context) error {
+	if e.chart.Schema == nil {
+		return nil
+	}
+	defer e.subs.drain()
+	e.prompt.Lock()
+	defer e.prompt.Unlock()
+	for {
+		e.mu.Lock()
+		missing := e.chart.Schema.Validate(e.schemaValues()).Missing()
+		e.mu.Unlock()
+		if len(missing) == 0 {
+			break
+		}
+		for _, m := range missing {
+			val, err := e.askSchema(ctx, m.Path, e.chart.Schema.Lookup(m.Path))
+			if err != nil {
+				return err
+			}
+			e.set(m.Path, val, "prompt")
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.validateSchema()
+}
+
+// validateSchema checks the resolved settings against the chart's schema.
+// e.mu must be held.
+func (e *Enforcer) validateSchema() error {
+	if e.chart == nil || e.chart.Schema == nil {
+		return nil
+	}
+	if errs := e.chart.Schema.Validate(e.schemaValues()); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// schemaValues returns the resolved settings nested as the schema expects
+// them. viper lowercases keys, so each one is put back under the spelling
+// the chart's values use, and values the schema finds missing are looked up
+// in viper, which also sees the environment. e.mu must be held.
+func (e *Enforcer) schemaValues() map[string]interface{} {
+	ref := e.chart.Values
+	if e.overlay != nil {
+		ref = e.overlaid
+	}
+	vals := helm.Merge(map[string]interface{}{}, ref)
+	for k, v := range settings(e.v) {
+		setFold(vals, k, v)
+	}
+	filled := map[string]bool{}
+	for {
+		found := false
+		for _, m := range e.chart.Schema.Validate(vals).Missing() {
+			if filled[m.Path] {
+				continue
+			}
+			filled[m.Path] = true
+			if val := e.v.Get(m.Path); val != nil && val != "" {
+				helm.SetPath(vals, m.Path, val)
+				found = true
+			}
+		}
+		if !found {
+			return vals
+		}
+	}
+}
+
+// setFold sets the value at the dotted path in vals, following the keys
+// already there whatever their case.
+func setFold(vals map[string]interface{}, path string, val interface{}) {
+	keys := strings.Split(path, ".")
+	m := vals
+	for i, k := range keys {
+		for existing := range m {
+			if strings.EqualFold(existing, k) {
+				k = existing
+				break
+			}
+		}
+		if i == len(keys)-1 {
+			m[k] = val
+			return
+		}
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+}
+
+// askSchema prompts for the value at path, converting the answer to the type
+// the schema expects.
+func (e *Enforcer) askSchema(ctx context.Context, path string, s *helm.Schema) (interface{}, error) {
+	q := "Please provide a value for the following key: " + path
+	def := ""
+	typ := "string"
+	if s != nil {
+		if s.Description != "" {
+			q = q + " (" + s.Description + ")"
+		}
+		if s.Default != nil {
+			def = fmt.Sprint(s.Default)
+		}
+		if types := s.Types(); len(types) > 0 {
+			typ = types[0]
+		}
+	}
+	if computed := e.commandDefault(ctx, path); computed != "" {
+		def = computed
+	}
+	ans, err := e.ask(ctx, q, def)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case "integer":
+		return strconv.Atoi(ans)
+	case "number":
+		return strconv.ParseFloat(ans, 64)
+	case "boolean":
+		return strconv.ParseBool(ans)
+	case "array":
+		slice, err := csvlist.Parse(ans)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(slice))
+		for i, item := range slice {
+			out[i] = item
+		}
+		return out, nil
+	case "object":
+		m, err := csvlist.ParseMap(ans)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[k] = v
+		}
+		return out, nil
+	}
+	return ans, nil
+}
+
+// Overlay applies helm values files and --set/--set-string overrides on top
+// of the chart's values.yaml, in the order helm install would. The merged
+// values replace the Enforcer's config, so requirements and the schema are
+// enforced against what the release would actually receive, and Source
+// reports which file or expression supplied each key.
+//
+// Each call replaces the previous overlay rather than adding to it, and
+// overlay values are never written back to the base values file.
+func (e *Enforcer) Overlay(o *helm.Overlay) error {
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var b []byte
+	if f := e.v.ConfigFileUsed(); f != "" {
+		var err error
+		if b, err = ioutil.ReadFile(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	before := settings(e.v)
+	prev, origins := e.overlay, e.origins
+	e.overlay = o
+	e.origins = nil
+	if err := e.loadConfig(b); err != nil {
+		e.overlay, e.origins = prev, origins
+		return err
+	}
+	e.commit(before)
+	return nil
+}
+
+// applyOverlay resolves the overlay on top of base, the values read from
+// the config file, and returns the values that make up the config layer.
+// e.mu must be held.
+func (e *Enforcer) applyOverlay(base map[string]interface{}) (map[string]interface{}, error) {
+	file := e.v.ConfigFileUsed()
+	if e.chart != nil {
+		file = e.chart.ValuesFile()
+	}
+	res, err := e.overlay.Resolve(base, file)
+	if err != nil {
+		return nil, err
+	}
+	e.overlaid = res.Values
+	for k, src := range res.Sources {
+		e.setSource(k, src)
+	}
+	return res.Values, nil
+}
+
+// fromOverlay reports whether the current value of key is one the overlay
+// put there, rather than one from the base values or set since. e.mu must
+// be held.
+func (e *Enforcer) fromOverlay(key string, val interface{}) bool {
+	if e.overlay == nil {
+		return false
+	}
+	over, ok := lookupFold(e.overlaid, key)
+	if !ok || !reflect.DeepEqual(over, val) {
+		return false
+	}
+	base, ok := lookupFold(e.base, key)
+	return !ok || !reflect.DeepEqual(base, over)
+}
+
+// lookupFold returns the value at the dotted path key in vals, matching
+// each key whatever its case.
+func lookupFold(vals map[string]interface{}, key string) (interface{}, bool) {
+	var cur interface{} = vals
+	for _, k := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for mk, mv := range m {
+			if strings.EqualFold(mk, k) {
+				cur, found = mv, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,102 @@
+package helm
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ChartFile  = "Chart.yaml"
+	ValuesFile = "values.yaml"
+	SchemaFile = "values.schema.json"
+)
+
+// Chart is a Helm chart found on disk along with its default values and,
+// when the chart ships one, its values schema.
+type Chart struct {
+	Dir         string                 `yaml:"-"`
+	APIVersion  string                 `yaml:"apiVersion"`
+	Name        string                 `yaml:"name"`
+	Version     string                 `yaml:"version"`
+	AppVersion  string                 `yaml:"appVersion"`
+	Description string                 `yaml:"description"`
+	Schema      *Schema                `yaml:"-"`
+	Values      map[string]interface{} `yaml:"-"`
+}
+
+// FindChart searches each path, and the directories directly beneath it, for
+// a Chart.yaml and loads the first chart it finds.
+func FindChart(paths ...string) (*Chart, error) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if isFile(filepath.Join(p, ChartFile)) {
+			return LoadChart(p)
+		}
+		matches, _ := filepath.Glob(filepath.Join(p, "*", ChartFile))
+		if len(matches) > 0 {
+			return LoadChart(filepath.Dir(matches[0]))
+		}
+	}
+	return nil, errors.New("no " + ChartFile + " was found")
+}
+
+// LoadChart reads the chart metadata, values.yaml and values.schema.json
+// from dir. The values and schema files are optional.
+func LoadChart(dir string) (*Chart, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ChartFile))
+	if err != nil {
+		return nil, err
+	}
+	c := &Chart{Dir: dir, Values: map[string]interface{}{}}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, errors.New("failed to parse " + filepath.Join(dir, ChartFile) + ": " + err.Error())
+	}
+	if isFile(c.ValuesFile()) {
+		vals, err := ReadValues(c.ValuesFile())
+		if err != nil {
+			return nil, err
+		}
+		c.Values = vals
+	}
+	if isFile(c.SchemaFile()) {
+		s, err := LoadSchema(c.SchemaFile())
+		if err != nil {
+			return nil, err
+		}
+		c.Schema = s
+	}
+	return c, nil
+}
+
+// ValuesFile returns the path of the chart's default values file.
+func (c *Chart) ValuesFile() string {
+	return filepath.Join(c.Dir, ValuesFile)
+}
+
+// SchemaFile returns the path of the chart's values schema.
+func (c *Chart) SchemaFile() string {
+	return filepath.Join(c.Dir, SchemaFile)
+}
+
+// Validate checks the chart's values against its schema. A chart without a
+// schema is always valid.
+func (c *Chart) Validate() error {
+	if c.Schema == nil {
+		return nil
+	}
+	if errs := c.Schema.Validate(c.Values); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
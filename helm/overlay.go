@@ -0,0 +1,270 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Overlay is the ordered set of overrides helm applies on top of a chart's
+// values.yaml: values files given with -f, then --set and --set-string
+// expressions.
+type Overlay struct {
+	Files     []string
+	Set       []string
+	SetString []string
+}
+
+// Resolved holds the effective values of an Overlay together with the
+// source that supplied each leaf key.
+type Resolved struct {
+	Values  map[string]interface{}
+	Sources map[string]string
+}
+
+// Resolve merges the overlay onto base, which was read from baseSource,
+// using the same precedence and deep-merge rules as helm install.
+func (o *Overlay) Resolve(base map[string]interface{}, baseSource string) (*Resolved, error) {
+	r := &Resolved{Values: map[string]interface{}{}, Sources: map[string]string{}}
+	r.apply(Normalize(base).(map[string]interface{}), baseSource)
+	for _, f := range o.Files {
+		vals, err := ReadValues(f)
+		if err != nil {
+			return nil, err
+		}
+		r.apply(vals, f)
+	}
+	for _, expr := range o.Set {
+		vals := map[string]interface{}{}
+		if err := ParseSet(vals, expr, false); err != nil {
+			return nil, err
+		}
+		r.apply(vals, "--set "+expr)
+	}
+	for _, expr := range o.SetString {
+		vals := map[string]interface{}{}
+		if err := ParseSet(vals, expr, true); err != nil {
+			return nil, err
+		}
+		r.apply(vals, "--set-string "+expr)
+	}
+	leaves := map[string]bool{}
+	for _, k := range Leaves(r.Values) {
+		leaves[k] = true
+	}
+	for k := range r.Sources {
+		if !leaves[k] {
+			delete(r.Sources, k)
+		}
+	}
+	return r, nil
+}
+
+func (r *Resolved) apply(vals map[string]interface{}, source string) {
+	Merge(r.Values, vals)
+	for _, k := range Leaves(vals) {
+		r.Sources[k] = source
+	}
+}
+
+// Keys returns the resolved leaf keys in sorted order.
+func (r *Resolved) Keys() []string {
+	keys := Leaves(r.Values)
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge deep-merges src into dst the way helm coalesces values: maps are
+// merged key by key, any other value replaces what was there, and a null
+// value deletes the key.
+func Merge(dst, src map[string]interface{}) map[string]interface{} {
+	for k, sv := range src {
+		if sv == nil {
+			delete(dst, k)
+			continue
+		}
+		sm, sok := sv.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+		if sok && dok {
+			Merge(dm, sm)
+			continue
+		}
+		if sok {
+			dst[k] = Merge(map[string]interface{}{}, sm)
+			continue
+		}
+		dst[k] = sv
+	}
+	return dst
+}
+
+// Leaves returns the dotted paths of every non-map value in vals.
+func Leaves(vals map[string]interface{}) []string {
+	var out []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				walk(join(prefix, k), sub)
+				continue
+			}
+			out = append(out, join(prefix, k))
+		}
+	}
+	walk("", vals)
+	return out
+}
+
+// ParseSet applies a --set style expression such as
+// "image.tag=1.2,hosts[0]=a.example.com,tags={a,b}" to vals. When
+// forceString is set every value is kept as a string, as --set-string does.
+// Commas, dots and equals signs can be escaped with a backslash.
+func ParseSet(vals map[string]interface{}, expr string, forceString bool) error {
+	for _, assign := range splitEscaped(expr, ',', true) {
+		if assign == "" {
+			continue
+		}
+		parts := splitEscaped(assign, '=', false)
+		if len(parts) < 2 {
+			return errors.New("key " + strconv.Quote(assign) + " has no value")
+		}
+		key, raw := parts[0], strings.Join(parts[1:], "=")
+		var val interface{}
+		if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+			var list []interface{}
+			for _, item := range splitEscaped(raw[1:len(raw)-1], ',', false) {
+				list = append(list, typedVal(unescape(item), forceString))
+			}
+			val = list
+		} else {
+			val = typedVal(unescape(raw), forceString)
+		}
+		if err := setKey(vals, splitEscaped(key, '.', false), val); err != nil {
+			return fmt.Errorf("failed parsing --set data %q: %s", expr, err)
+		}
+	}
+	return nil
+}
+
+// splitEscaped splits s on sep, ignoring separators preceded by a backslash.
+// When braces is set, separators inside {...} lists are ignored as well.
+func splitEscaped(s string, sep rune, braces bool) []string {
+	var out []string
+	var cur strings.Builder
+	depth := 0
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune('\\')
+			cur.WriteRune(r)
+			escaped = false
+			continue
+		case r == '\\':
+			escaped = true
+			continue
+		case braces && r == '{':
+			depth++
+		case braces && r == '}' && depth > 0:
+			depth--
+		case r == sep && depth == 0:
+			out = append(out, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	if escaped {
+		cur.WriteRune('\\')
+	}
+	return append(out, cur.String())
+}
+
+func unescape(s string) string {
+	var out strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+func typedVal(s string, forceString bool) interface{} {
+	if forceString {
+		return s
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if s != "0" && strings.HasPrefix(s, "0") {
+		return s
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	return s
+}
+
+// setKey assigns val at the path made of keys, each of which may carry list
+// indexes like "hosts[0]".
+func setKey(m map[string]interface{}, keys []string, val interface{}) error {
+	name, idx, err := parseIndex(unescape(keys[0]))
+	if err != nil {
+		return err
+	}
+	last := len(keys) == 1
+	if idx < 0 {
+		if last {
+			m[name] = val
+			return nil
+		}
+		next, ok := m[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[name] = next
+		}
+		return setKey(next, keys[1:], val)
+	}
+	list, _ := m[name].([]interface{})
+	for len(list) <= idx {
+		list = append(list, nil)
+	}
+	m[name] = list
+	if last {
+		list[idx] = val
+		return nil
+	}
+	next, ok := list[idx].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+		list[idx] = next
+	}
+	return setKey(next, keys[1:], val)
+}
+
+func parseIndex(key string) (string, int, error) {
+	open := strings.IndexRune(key, '[')
+	if open < 0 {
+		return key, -1, nil
+	}
+	if !strings.HasSuffix(key, "]") {
+		return "", 0, errors.New("invalid list index in key " + strconv.Quote(key))
+	}
+	idx, err := strconv.Atoi(key[open+1 : len(key)-1])
+	if err != nil || idx < 0 {
+		return "", 0, errors.New("invalid list index in key " + strconv.Quote(key))
+	}
+	return key[:open], idx, nil
+}
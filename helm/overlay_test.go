@@ -0,0 +1,124 @@
+package helm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		expr   string
+		force  bool
+		want   map[string]interface{}
+		hasErr bool
+	}{
+		{expr: "image.tag=1.2,replicas=3,debug=true", want: map[string]interface{}{
+			"image":    map[string]interface{}{"tag": "1.2"},
+			"replicas": int64(3),
+			"debug":    true,
+		}},
+		{expr: "replicas=3,zip=007", force: true, want: map[string]interface{}{"replicas": "3", "zip": "007"}},
+		{expr: "zip=007,off=null", want: map[string]interface{}{"zip": "007", "off": nil}},
+		{expr: "tags={a,b,1}", want: map[string]interface{}{"tags": []interface{}{"a", "b", int64(1)}}},
+		{expr: `hosts[1].name=b\,c,a\.b=x\=y`, want: map[string]interface{}{
+			"hosts": []interface{}{nil, map[string]interface{}{"name": "b,c"}},
+			"a.b":   "x=y",
+		}},
+		{expr: "novalue", hasErr: true},
+		{expr: "hosts[x]=a", hasErr: true},
+	}
+	for _, tt := range tests {
+		vals := map[string]interface{}{}
+		err := ParseSet(vals, tt.expr, tt.force)
+		if tt.hasErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(vals, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.expr, vals, tt.want)
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	dir := writeChart(t, map[string]string{
+		"prod.yaml": "image:\n  tag: v2\nreplicas: 3\nresources: null\n",
+	})
+	base := map[string]interface{}{
+		"image":     map[interface{}]interface{}{"repository": "app", "tag": "v1"},
+		"replicas":  1,
+		"resources": map[string]interface{}{"cpu": "1"},
+	}
+	o := &Overlay{
+		Files:     []string{filepath.Join(dir, "prod.yaml")},
+		Set:       []string{"replicas=5"},
+		SetString: []string{"image.tag=3"},
+	}
+	r, err := o.Resolve(base, "values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "app", "tag": "3"},
+		"replicas": int64(5),
+	}
+	if !reflect.DeepEqual(r.Values, want) {
+		t.Fatalf("resolved %#v, want %#v", r.Values, want)
+	}
+	sources := map[string]string{
+		"image.repository": "values.yaml",
+		"image.tag":        "--set-string image.tag=3",
+		"replicas":         "--set replicas=5",
+	}
+	if !reflect.DeepEqual(r.Sources, sources) {
+		t.Fatalf("sources are %v, want %v", r.Sources, sources)
+	}
+	if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"image.repository", "image.tag", "replicas"}) {
+		t.Fatalf("keys are %v", keys)
+	}
+	if _, ok := base["resources"]; !ok {
+		t.Fatal("resolving modified the base values")
+	}
+
+	bad := &Overlay{Files: []string{filepath.Join(dir, "missing.yaml")}}
+	if _, err := bad.Resolve(base, "values.yaml"); err == nil {
+		t.Fatal("expected an error for a missing values file")
+	}
+}
+
+func TestValuesPaths(t *testing.T) {
+	dir := writeChart(t, map[string]string{ValuesFile: "image:\n  tag: v1\n1: one\n"})
+	vals, err := ReadValues(filepath.Join(dir, ValuesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := Lookup(vals, "image.tag"); !ok || v != "v1" {
+		t.Fatalf("Lookup(image.tag) = %v, %v", v, ok)
+	}
+	if vals["1"] != "one" {
+		t.Fatal("non-string keys were not normalized")
+	}
+	if _, ok := Lookup(vals, "image.tag.deeper"); ok {
+		t.Fatal("Lookup walked into a scalar")
+	}
+	SetPath(vals, "db.host", "h")
+	if v, _ := Lookup(vals, "db.host"); v != "h" {
+		t.Fatalf("SetPath did not set db.host: %v", vals)
+	}
+	if !DeletePath(vals, "db.host") {
+		t.Fatal("DeletePath found nothing to delete")
+	}
+	if _, ok := vals["db"]; ok {
+		t.Fatal("DeletePath left an empty parent map")
+	}
+	if DeletePath(vals, "image.nope") {
+		t.Fatal("DeletePath reported deleting a missing key")
+	}
+}
@@ -0,0 +1,290 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema that Helm charts use in
+// values.schema.json.
+type Schema struct {
+	Type                 interface{}        `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// Violation is a single place where values do not satisfy a schema.
+type Violation struct {
+	Path    string
+	Message string
+	// Missing is set when the violation is a required property that has no
+	// value, which can be fixed by asking for one.
+	Missing bool
+}
+
+func (v *Violation) Error() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// Errors is the list of violations found by Validate.
+type Errors []*Violation
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Error()
+	}
+	return "values do not match " + SchemaFile + ":\n" + strings.Join(msgs, "\n")
+}
+
+// Missing returns the violations for required values that are not set.
+func (e Errors) Missing() Errors {
+	var out Errors
+	for _, v := range e {
+		if v.Missing {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// LoadSchema reads a values.schema.json file.
+func LoadSchema(path string) (*Schema, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	return s, nil
+}
+
+// Types returns the types the schema allows, or nil if any type is allowed.
+func (s *Schema) Types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Lookup returns the schema of the value at a dotted path.
+func (s *Schema) Lookup(path string) *Schema {
+	cur := s
+	for _, k := range strings.Split(path, ".") {
+		if cur == nil || cur.Properties == nil {
+			return nil
+		}
+		cur = cur.Properties[k]
+	}
+	return cur
+}
+
+// Validate checks vals against the schema and returns every violation found.
+func (s *Schema) Validate(vals interface{}) Errors {
+	var errs Errors
+	s.validate("", vals, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *Errors) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, &Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if types := s.Types(); len(types) > 0 && !matchesType(types, v) {
+		fail("expected %s but got %s", strings.Join(types, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		fail("must be one of %v", s.Enum)
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, req := range s.Required {
+			if val, ok := t[req]; !ok || val == nil {
+				*errs = append(*errs, &Violation{Path: join(path, req), Message: "is required", Missing: true})
+			}
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if t[k] == nil {
+				continue
+			}
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(join(path, k), t[k], errs)
+				continue
+			}
+			switch ap := s.AdditionalProperties.(type) {
+			case bool:
+				if !ap {
+					*errs = append(*errs, &Violation{Path: join(path, k), Message: "is not allowed"})
+				}
+			case map[string]interface{}:
+				if sub := toSchema(ap); sub != nil {
+					sub.validate(join(path, k), t[k], errs)
+				}
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(t) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(t) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range t {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(t) < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(t) > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				fail("invalid pattern %q in schema: %s", s.Pattern, err)
+			} else if !re.MatchString(t) {
+				fail("must match pattern %q", s.Pattern)
+			}
+		}
+	default:
+		if n, ok := toFloat(v); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				fail("must be greater than or equal to %v", *s.Minimum)
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				fail("must be less than or equal to %v", *s.Maximum)
+			}
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func toSchema(m map[string]interface{}) *Schema {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	s := &Schema{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil
+	}
+	return s
+}
+
+func matchesType(types []string, v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		if n, _ := toFloat(t); n == float64(int64(n)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["image", "replicas"],
+  "additionalProperties": false,
+  "properties": {
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string", "minLength": 1},
+        "tag": {"type": "string", "pattern": "^v[0-9]+"},
+        "pullPolicy": {"enum": ["Always", "IfNotPresent"]}
+      }
+    },
+    "replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+    "hosts": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  }
+}`
+
+func writeChart(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSchemaValidate(t *testing.T) {
+	dir := writeChart(t, map[string]string{SchemaFile: testSchema})
+	s, err := LoadSchema(filepath.Join(dir, SchemaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "app", "tag": "v1.2", "pullPolicy": "Always"},
+		"replicas": 3,
+		"hosts":    []interface{}{"a"},
+		"labels":   map[string]interface{}{"team": "core"},
+	}
+	if errs := s.Validate(valid); len(errs) > 0 {
+		t.Fatalf("valid values rejected: %v", errs)
+	}
+
+	invalid := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "latest", "pullPolicy": "Never"},
+		"replicas": 9,
+		"hosts":    []interface{}{"a", "b", 3},
+		"labels":   map[string]interface{}{"team": 1},
+		"extra":    true,
+	}
+	errs := s.Validate(invalid)
+	want := map[string]string{
+		"image.repository": "is required",
+		"image.tag":        "must match pattern",
+		"image.pullPolicy": "must be one of",
+		"replicas":         "less than or equal to 5",
+		"hosts":            "at most 2 items",
+		"hosts[2]":         "expected string but got",
+		"labels.team":      "expected string but got",
+		"extra":            "is not allowed",
+	}
+	got := map[string]string{}
+	for _, v := range errs {
+		got[v.Path] = v.Message
+	}
+	for path, msg := range want {
+		if !strings.Contains(got[path], msg) {
+			t.Errorf("%s: got %q, want a message containing %q", path, got[path], msg)
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("got %d violations, want %d:\n%v", len(errs), len(want), errs)
+	}
+	missing := errs.Missing()
+	if len(missing) != 1 || missing[0].Path != "image.repository" {
+		t.Errorf("missing values are %v, want only image.repository", missing)
+	}
+}
+
+func TestSchemaLookupAndTypes(t *testing.T) {
+	dir := writeChart(t, map[string]string{SchemaFile: testSchema})
+	s, err := LoadSchema(filepath.Join(dir, SchemaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub := s.Lookup("image.tag"); sub == nil || sub.Pattern != "^v[0-9]+" {
+		t.Fatalf("Lookup(image.tag) = %+v", sub)
+	}
+	if s.Lookup("image.nope") != nil || s.Lookup("replicas.deeper") != nil {
+		t.Fatal("Lookup found a schema for a path the schema does not describe")
+	}
+	multi := &Schema{Type: []interface{}{"string", "null"}}
+	if types := multi.Types(); len(types) != 2 || types[1] != "null" {
+		t.Fatalf("Types() = %v", types)
+	}
+	if (&Schema{}).Types() != nil {
+		t.Fatal("a schema without a type should allow any type")
+	}
+}
+
+func TestFindAndValidateChart(t *testing.T) {
+	dir := writeChart(t, map[string]string{
+		"charts/app/" + ChartFile:  "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"charts/app/" + ValuesFile: "image:\n  repository: app\nreplicas: 0\n",
+		"charts/app/" + SchemaFile: testSchema,
+	})
+	if _, err := FindChart(filepath.Join(dir, "empty")); err == nil {
+		t.Fatal("expected an error when no chart exists")
+	}
+	c, err := FindChart("", filepath.Join(dir, "charts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "app" || c.Dir != filepath.Join(dir, "charts", "app") || c.Schema == nil {
+		t.Fatalf("loaded %+v", c)
+	}
+	err = c.Validate()
+	if err == nil || !strings.Contains(err.Error(), "replicas: must be greater than or equal to 1") {
+		t.Fatalf("expected the replicas violation, got %v", err)
+	}
+	c.Values["replicas"] = 1
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Schema = nil
+	c.Values["replicas"] = "many"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("a chart without a schema should be valid: %v", err)
+	}
+}
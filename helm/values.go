@@ -0,0 +1,104 @@
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ReadValues parses a values file into a map with string keys at every level.
+func ReadValues(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	vals, _ := Normalize(raw).(map[string]interface{})
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+	return vals, nil
+}
+
+// Normalize converts the map[interface{}]interface{} values produced by the
+// yaml decoder into map[string]interface{} so values can be walked and
+// encoded as json.
+func Normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = Normalize(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[k] = Normalize(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = Normalize(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// Lookup returns the value at a dotted path such as "image.tag".
+func Lookup(vals map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = vals
+	for _, k := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// SetPath sets the value at a dotted path, creating intermediate maps as
+// needed.
+func SetPath(vals map[string]interface{}, path string, val interface{}) {
+	keys := strings.Split(path, ".")
+	m := vals
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = val
+}
+
+// DeletePath removes the value at a dotted path, along with any maps left
+// empty by removing it. It reports whether there was a value to remove.
+func DeletePath(vals map[string]interface{}, path string) bool {
+	keys := strings.SplitN(path, ".", 2)
+	if len(keys) == 1 {
+		_, ok := vals[path]
+		delete(vals, path)
+		return ok
+	}
+	next, ok := vals[keys[0]].(map[string]interface{})
+	if !ok || !DeletePath(next, keys[1]) {
+		return false
+	}
+	if len(next) == 0 {
+		delete(vals, keys[0])
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package require
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofunct/require/helm"
+)
+
+const testChart = "apiVersion: v2\nname: demo\nversion: 0.1.0\n"
+
+const testSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {
+      "type": "object",
+      "required": ["tag", "pullPolicy"],
+      "properties": {
+        "tag": {"type": "string"},
+        "pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]}
+      }
+    }
+  }
+}`
+
+func newTestHelmEnforcer(t *testing.T, values string) *Enforcer {
+	t.Helper()
+	dir := writeFiles(t, map[string]string{
+		"Chart.yaml":         testChart,
+		"values.yaml":        values,
+		"values.schema.json": testSchema,
+	})
+	setenv(t, "REQUIRE_HELM_PATH", dir)
+	e := NewHelmEnforcer()
+	if e.Chart() == nil {
+		t.Fatal("chart not found")
+	}
+	return e
+}
+
+func TestSchemaSeesEnvironment(t *testing.T) {
+	e := newTestHelmEnforcer(t, "image:\n  pullPolicy: Always\n")
+	setenv(t, "HELM_IMAGE_TAG", "v1")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := e.InitContext(ctx); err != nil {
+		t.Fatalf("Init prompted for a value the environment supplies: %v", err)
+	}
+	if got := e.GetString("image.tag"); got != "v1" {
+		t.Errorf("image.tag = %q, want v1", got)
+	}
+}
+
+func TestSchemaValidatesResolvedSettings(t *testing.T) {
+	e := newTestHelmEnforcer(t, "image:\n  tag: v1\n  pullPolicy: Always\n")
+	e.Set("image.pullPolicy", "Sometimes")
+	e.mu.Lock()
+	err := e.validateSchema()
+	e.mu.Unlock()
+	if err == nil {
+		t.Error("a value Set outside the schema's enum was accepted")
+	}
+	e.Set("image.pullPolicy", "IfNotPresent")
+	e.mu.Lock()
+	err = e.validateSchema()
+	e.mu.Unlock()
+	if err != nil {
+		t.Errorf("valid settings were rejected: %v", err)
+	}
+}
+
+func TestOverlaysDoNotStack(t *testing.T) {
+	e := newTestHelmEnforcer(t, "replicas: 1\nimage:\n  tag: v1\n  pullPolicy: Always\n")
+	if err := e.Overlay(&helm.Overlay{Set: []string{"replicas=5"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetString("replicas"); got != "5" {
+		t.Fatalf("replicas = %q, want 5", got)
+	}
+	if err := e.Overlay(&helm.Overlay{Set: []string{"image.tag=v2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.GetString("replicas"); got != "1" {
+		t.Errorf("replicas = %q after a second overlay, want 1", got)
+	}
+	if got := e.GetString("image.tag"); got != "v2" {
+		t.Errorf("image.tag = %q, want v2", got)
+	}
+	if src := e.Source("replicas"); !strings.HasSuffix(src, "values.yaml") {
+		t.Errorf("Source(replicas) = %q, want values.yaml", src)
+	}
+}
+
+func TestOverlayValuesAreNotPersisted(t *testing.T) {
+	e := newTestHelmEnforcer(t, "replicas: 1 # how many\nimage:\n  tag: v1\n  pullPolicy: Always\n")
+	if err := e.Overlay(&helm.Overlay{Set: []string{"replicas=5", "extra=yes"}}); err != nil {
+		t.Fatal(err)
+	}
+	e.Set("image.tag", "v3")
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, e.Chart().ValuesFile())
+	want := "replicas: 1 # how many\nimage:\n  tag: v3\n  pullPolicy: Always\n"
+	if got != want {
+		t.Errorf("values.yaml =\n%s\nwant\n%s", got, want)
+	}
+}
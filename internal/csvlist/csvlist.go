@@ -0,0 +1,36 @@
+// Package csvlist parses the comma separated lists and key=value pairs that
+// prompts, flags and defaults accept for list and map values.
+package csvlist
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+)
+
+// Parse splits s as a single CSV record, so items can be quoted to contain
+// commas. An empty s is an empty list.
+func Parse(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	return csv.NewReader(strings.NewReader(s)).Read()
+}
+
+// ParseMap parses a list of key=value or key:value pairs, split at the
+// first = or :.
+func ParseMap(s string) (map[string]string, error) {
+	items, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(items))
+	for _, item := range items {
+		i := strings.IndexAny(item, "=:")
+		if i < 0 {
+			return nil, errors.New("expected key=value, got " + item)
+		}
+		m[strings.TrimSpace(item[:i])] = item[i+1:]
+	}
+	return m, nil
+}
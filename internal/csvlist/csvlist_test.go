@@ -0,0 +1,42 @@
+package csvlist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{"a", []string{"a"}},
+		{"a,b,c", []string{"a", "b", "c"}},
+		{`a,"b,c"`, []string{"a", "b,c"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	got, err := ParseMap("a=1,b:2,c=x=y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "1", "b": "2", "c": "x=y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMap = %v, want %v", got, want)
+	}
+	if _, err := ParseMap("a=1,b"); err == nil {
+		t.Error("ParseMap accepted an item without a separator")
+	}
+}
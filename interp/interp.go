@@ -0,0 +1,199 @@
+// Package interp expands references between config values, such as
+// "postgres://${db.user}@${db.host}/app" or "${env:HOME}/data".
+package interp
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// Expander expands ${...} references in config values:
+//
+//	${key}            the value of another key
+//	${env:NAME}       the environment variable NAME
+//	${key:-fallback}  the value of key, or fallback when it is unset or empty
+//	$${...}           a literal ${...}
+//
+// References may be nested, as in ${db.${env:STAGE}.host}, and values may
+// refer to keys whose values hold references of their own.
+type Expander struct {
+	// Lookup returns the raw value of a key.
+	Lookup func(key string) (interface{}, bool)
+	// Env looks up environment variables. It defaults to os.LookupEnv.
+	Env func(name string) (string, bool)
+
+	done  map[string]string
+	refs  map[string][]string
+	stack []string
+}
+
+// CycleError is returned when keys refer to each other in a loop.
+type CycleError struct {
+	Keys []string
+}
+
+func (e *CycleError) Error() string {
+	return "interpolation cycle: " + strings.Join(e.Keys, " -> ")
+}
+
+// HasRefs reports whether s contains anything to expand.
+func HasRefs(s string) bool {
+	return strings.Contains(s, "${")
+}
+
+// Key expands the value of key and returns it along with every key it
+// referred to, directly or through other keys. Environment variables are
+// listed as env:NAME.
+func (x *Expander) Key(key string) (string, []string, error) {
+	if x.done == nil {
+		x.done = map[string]string{}
+		x.refs = map[string][]string{}
+	}
+	if val, ok := x.done[key]; ok {
+		return val, x.refs[key], nil
+	}
+	for i, k := range x.stack {
+		if k == key {
+			return "", nil, &CycleError{Keys: append(append([]string{}, x.stack[i:]...), key)}
+		}
+	}
+	raw, ok := x.Lookup(key)
+	if !ok {
+		return "", nil, errors.New(key + " is not set")
+	}
+	x.stack = append(x.stack, key)
+	defer func() { x.stack = x.stack[:len(x.stack)-1] }()
+	var refs []string
+	val, err := x.expand(cast.ToString(raw), &refs)
+	if err != nil {
+		return "", nil, err
+	}
+	x.done[key] = val
+	x.refs[key] = refs
+	return val, refs, nil
+}
+
+// String expands the references in s.
+func (x *Expander) String(s string) (string, []string, error) {
+	var refs []string
+	val, err := x.expand(s, &refs)
+	return val, refs, err
+}
+
+func (x *Expander) expand(s string, refs *[]string) (string, error) {
+	var out strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			out.WriteString(s[:i-1])
+			end := closing(s, i+2)
+			if end < 0 {
+				return "", errors.New("unterminated reference in " + s)
+			}
+			out.WriteString(s[i : end+1])
+			s = s[end+1:]
+			continue
+		}
+		out.WriteString(s[:i])
+		end := closing(s, i+2)
+		if end < 0 {
+			return "", errors.New("unterminated reference in " + s)
+		}
+		val, err := x.ref(s[i+2:end], refs)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(val)
+		s = s[end+1:]
+	}
+}
+
+// ref resolves the inside of a single ${...}.
+func (x *Expander) ref(body string, refs *[]string) (string, error) {
+	name, fallback, hasFallback := body, "", false
+	if i := topLevelIndex(body, ":-"); i >= 0 {
+		name, fallback, hasFallback = body[:i], body[i+2:], true
+	}
+	name, err := x.expand(name, refs)
+	if err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "env:") {
+		env := x.Env
+		if env == nil {
+			env = os.LookupEnv
+		}
+		if val, ok := env(strings.TrimPrefix(name, "env:")); ok && val != "" {
+			*refs = appendUnique(*refs, name)
+			return val, nil
+		}
+	} else if raw, ok := x.Lookup(name); ok && raw != nil && cast.ToString(raw) != "" {
+		val, sub, err := x.Key(name)
+		if err != nil {
+			return "", err
+		}
+		*refs = appendUnique(*refs, name)
+		for _, r := range sub {
+			*refs = appendUnique(*refs, r)
+		}
+		return val, nil
+	}
+	if hasFallback {
+		return x.expand(fallback, refs)
+	}
+	if len(x.stack) > 0 {
+		return "", errors.New(x.stack[len(x.stack)-1] + " refers to " + name + ", which is not set")
+	}
+	return "", errors.New("reference to " + name + ", which is not set")
+}
+
+// closing returns the index of the brace closing a reference whose body
+// starts at from, skipping over nested references.
+func closing(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && i > 0 && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// topLevelIndex finds sep in body outside of nested references.
+func topLevelIndex(body, sep string) int {
+	depth := 0
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '{' && i > 0 && body[i-1] == '$':
+			depth++
+		case body[i] == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(body[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
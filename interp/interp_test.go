@@ -0,0 +1,104 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func expander(vals map[string]interface{}, env map[string]string) *Expander {
+	return &Expander{
+		Lookup: func(key string) (interface{}, bool) {
+			v, ok := vals[key]
+			return v, ok
+		},
+		Env: func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		},
+	}
+}
+
+func TestKey(t *testing.T) {
+	x := expander(map[string]interface{}{
+		"db.user":      "app",
+		"db.host":      "${db.${env:STAGE}.host}",
+		"db.prod.host": "db.internal",
+		"db.url":       "postgres://${db.user}@${db.host}/app",
+		"port":         5432,
+		"addr":         "${db.prod.host}:${port}",
+		"literal":      "$${db.user}",
+		"fallback":     "${missing:-${db.user}}",
+		"empty":        "",
+		"defaulted":    "${empty:-none}",
+	}, map[string]string{"STAGE": "prod"})
+
+	tests := []struct {
+		key, want string
+		refs      []string
+	}{
+		{"db.url", "postgres://app@db.internal/app", []string{"db.user", "db.host", "env:STAGE", "db.prod.host"}},
+		{"addr", "db.internal:5432", []string{"db.prod.host", "port"}},
+		{"literal", "${db.user}", nil},
+		{"fallback", "app", []string{"db.user"}},
+		{"defaulted", "none", nil},
+	}
+	for _, tt := range tests {
+		got, refs, err := x.Key(tt.key)
+		if err != nil {
+			t.Errorf("%s: %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+		}
+		if !reflect.DeepEqual(refs, tt.refs) {
+			t.Errorf("%s refers to %v, want %v", tt.key, refs, tt.refs)
+		}
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	x := expander(map[string]interface{}{
+		"a":          "${b}",
+		"b":          "${c}",
+		"c":          "${a}",
+		"dangling":   "${nowhere}",
+		"unfinished": "${a",
+	}, nil)
+
+	_, _, err := x.Key("a")
+	cycle, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	if want := []string{"a", "b", "c", "a"}; !reflect.DeepEqual(cycle.Keys, want) {
+		t.Errorf("cycle is %v, want %v", cycle.Keys, want)
+	}
+	if _, _, err := x.Key("dangling"); err == nil || !strings.Contains(err.Error(), "nowhere") {
+		t.Errorf("expected an error naming the missing key, got %v", err)
+	}
+	if _, _, err := x.Key("unfinished"); err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Errorf("expected an unterminated reference error, got %v", err)
+	}
+	if _, _, err := x.Key("unknown"); err == nil {
+		t.Error("expected an error for a key that is not set")
+	}
+}
+
+func TestString(t *testing.T) {
+	x := expander(map[string]interface{}{"name": "app"}, map[string]string{"HOME": "/home/app"})
+	got, refs, err := x.String("${env:HOME}/${name}/${env:UNSET:-data}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/home/app/app/data" {
+		t.Errorf("got %q", got)
+	}
+	if want := []string{"env:HOME", "name"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs are %v, want %v", refs, want)
+	}
+	if HasRefs("plain") || !HasRefs("a ${b}") {
+		t.Error("HasRefs misreports")
+	}
+}
@@ -0,0 +1,103 @@
+package require
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/interp"
+	"go.uber.org/zap"
+)
+
+// expansion records how an interpolated value was built.
+type expansion struct {
+	raw   string
+	value string
+	refs  []string
+}
+
+// Interpolate expands ${key}, ${env:NAME} and ${key:-fallback} references
+// in every string value. Init calls it once sources, secrets and prompts
+// have been resolved, Watch after every reload and Set after every change.
+// Expanded values are written back as the expressions they came from.
+func (e *Enforcer) Interpolate() error {
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	before := settings(e.v)
+	if err := e.interpolate(); err != nil {
+		return err
+	}
+	e.commit(before)
+	return nil
+}
+
+// Expansion returns the value key held before interpolation and the keys
+// its expanded value was built from. ok is false if key held no references.
+func (e *Enforcer) Expansion(key string) (raw string, refs []string, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	x, ok := e.expanded[strings.ToLower(key)]
+	if !ok {
+		return "", nil, false
+	}
+	return x.raw, append([]string(nil), x.refs...), true
+}
+
+// interpolate expands the references in every string value, writing the
+// results back to the layer each value came from. e.mu must be held.
+func (e *Enforcer) interpolate() error {
+	vals := settings(e.v)
+	// Values expanded by an earlier pass are expanded again from the value
+	// they were written as, so escapes are not undone twice and references
+	// pick up changes to the keys they use.
+	for k, prev := range e.expanded {
+		if vals[k] != prev.value {
+			delete(e.expanded, k)
+			continue
+		}
+		vals[k] = prev.raw
+	}
+	x := &interp.Expander{
+		Lookup: func(key string) (interface{}, bool) {
+			val, ok := vals[strings.ToLower(key)]
+			return val, ok
+		},
+	}
+	config := map[string]interface{}{}
+	for k, v := range vals {
+		s, ok := v.(string)
+		if !ok || !interp.HasRefs(s) {
+			continue
+		}
+		val, refs, err := x.Key(k)
+		if err != nil {
+			return errors.New("cannot interpolate " + k + ": " + err.Error())
+		}
+		for _, r := range refs {
+			if e.secret[strings.ToLower(r)] {
+				e.markSecret(k)
+			}
+		}
+		if e.expanded == nil {
+			e.expanded = map[string]expansion{}
+		}
+		e.expanded[k] = expansion{raw: s, value: val, refs: refs}
+		e.log().Debug("value interpolated", zap.String("key", k), zap.Strings("refs", refs))
+		if val == e.v.Get(k) {
+			continue
+		}
+		// Runtime values live in viper's override layer, which would shadow
+		// anything merged into the config layer.
+		switch src := e.origins[k]; {
+		case src == "set" || src == "prompt" || strings.HasPrefix(src, "env "):
+			e.v.Set(k, val)
+		default:
+			helm.SetPath(config, k, val)
+		}
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return e.v.MergeConfigMap(config)
+}
@@ -0,0 +1,50 @@
+package require
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newInterpEnforcer(t *testing.T, config string) (*Enforcer, string) {
+	t.Helper()
+	file := filepath.Join(writeFiles(t, map[string]string{"app.yaml": config}), "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	e.Requirements = []string{"db.url"}
+	initContext(t, e)
+	return e, file
+}
+
+func TestSetReinterpolates(t *testing.T) {
+	e, _ := newInterpEnforcer(t, "db:\n  host: a\n  url: postgres://${db.host}/app\n")
+	if got := e.GetString("db.url"); got != "postgres://a/app" {
+		t.Fatalf("db.url = %q, want postgres://a/app", got)
+	}
+	var changed interface{}
+	e.OnChange("db.url", func(old, new interface{}) { changed = new })
+	e.Set("db.host", "b")
+	if got := e.GetString("db.url"); got != "postgres://b/app" {
+		t.Errorf("db.url = %q after setting db.host, want postgres://b/app", got)
+	}
+	if changed != "postgres://b/app" {
+		t.Errorf("OnChange(db.url) saw %v, want postgres://b/app", changed)
+	}
+	e.Set("greeting", "hi ${db.host}")
+	if got := e.GetString("greeting"); got != "hi b" {
+		t.Errorf("greeting = %q, want a Set value to be expanded", got)
+	}
+}
+
+func TestInterpolatedValuesAreNotPersisted(t *testing.T) {
+	e, file := newInterpEnforcer(t, "db:\n  password: hunter2\n  url: postgres://admin:${db.password}@db/app\n")
+	e.Set("db.password", "s3cret")
+	if got := e.GetString("db.url"); got != "postgres://admin:s3cret@db/app" {
+		t.Errorf("db.url = %q", got)
+	}
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	want := "db:\n  password: s3cret\n  url: postgres://admin:${db.password}@db/app\n"
+	if got := readFile(t, file); got != want {
+		t.Errorf("config file =\n%s\nwant\n%s", got, want)
+	}
+}
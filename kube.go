@@ -0,0 +1,31 @@
+package require
+
+import (
+	"strings"
+
+	"github.com/gofunct/require/kube"
+)
+
+// Kubernetes renders the Enforcer's resolved values as a ConfigMap and a
+// Secret named name, putting every secret key in the Secret, along with
+// the env and envFrom entries that hand them to a container. Reading the
+// manifest back with a source.Kube, or mounting it, gives the same values.
+func (e *Enforcer) Kubernetes(name, namespace string) (*kube.Bundle, error) {
+	vals := e.snapshot()
+	e.mu.Lock()
+	secrets := make(map[string]bool, len(e.secret))
+	for k, v := range e.secret {
+		secrets[k] = v
+	}
+	e.mu.Unlock()
+	leaves := map[string]interface{}{}
+	for k, v := range vals {
+		if _, ok := v.(map[string]interface{}); ok {
+			continue
+		}
+		leaves[k] = v
+	}
+	return kube.Render(name, namespace, e.EnvPrefix, leaves, func(key string) bool {
+		return secrets[strings.ToLower(key)]
+	})
+}
@@ -0,0 +1,237 @@
+// Package kube renders resolved config values as a Kubernetes ConfigMap and
+// Secret, with the env and envFrom entries a Deployment needs to receive
+// them, and reads such manifests and mounted ConfigMap directories back.
+package kube
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+type Metadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// Object is a ConfigMap or Secret. Data holds ConfigMap values as they
+// are and Secret values base64 encoded.
+type Object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   Metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+type KeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type ValueFrom struct {
+	ConfigMapKeyRef *KeyRef `yaml:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *KeyRef `yaml:"secretKeyRef,omitempty"`
+}
+
+// EnvVar is an entry of a container's env list.
+type EnvVar struct {
+	Name      string    `yaml:"name"`
+	ValueFrom ValueFrom `yaml:"valueFrom"`
+}
+
+type Ref struct {
+	Name string `yaml:"name"`
+}
+
+// EnvFrom is an entry of a container's envFrom list.
+type EnvFrom struct {
+	ConfigMapRef *Ref `yaml:"configMapRef,omitempty"`
+	SecretRef    *Ref `yaml:"secretRef,omitempty"`
+}
+
+// Bundle is everything a Deployment needs to receive a config.
+type Bundle struct {
+	ConfigMap *Object
+	Secret    *Object
+	// Env maps each key to the environment variable an Enforcer with the
+	// same EnvPrefix reads it from.
+	Env []EnvVar
+	// EnvFrom exposes every key as an environment variable of the same
+	// name, which is what an Enforcer looks up before prompting.
+	EnvFrom []EnvFrom
+}
+
+// Render builds a ConfigMap for the values not marked secret and a Secret
+// for the rest, both named name, keyed by config key.
+func Render(name, namespace, envPrefix string, vals map[string]interface{}, secret func(key string) bool) (*Bundle, error) {
+	b := &Bundle{}
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	meta := Metadata{Name: name, Namespace: namespace, Labels: map[string]string{"app.kubernetes.io/managed-by": "require"}}
+	for _, k := range keys {
+		if vals[k] == nil {
+			continue
+		}
+		val, err := format(vals[k])
+		if err != nil {
+			return nil, errors.New("cannot render " + k + ": " + err.Error())
+		}
+		ref := &KeyRef{Name: name, Key: k}
+		env := EnvVar{Name: EnvName(envPrefix, k)}
+		if secret(k) {
+			if b.Secret == nil {
+				b.Secret = &Object{APIVersion: "v1", Kind: "Secret", Metadata: meta, Type: "Opaque", Data: map[string]string{}}
+			}
+			b.Secret.Data[k] = base64.StdEncoding.EncodeToString([]byte(val))
+			env.ValueFrom.SecretKeyRef = ref
+		} else {
+			if b.ConfigMap == nil {
+				b.ConfigMap = &Object{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta, Data: map[string]string{}}
+			}
+			b.ConfigMap.Data[k] = val
+			env.ValueFrom.ConfigMapKeyRef = ref
+		}
+		b.Env = append(b.Env, env)
+	}
+	if b.ConfigMap != nil {
+		b.EnvFrom = append(b.EnvFrom, EnvFrom{ConfigMapRef: &Ref{Name: name}})
+	}
+	if b.Secret != nil {
+		b.EnvFrom = append(b.EnvFrom, EnvFrom{SecretRef: &Ref{Name: name}})
+	}
+	return b, nil
+}
+
+// EnvName returns the environment variable an Enforcer with envPrefix reads
+// key from.
+func EnvName(envPrefix, key string) string {
+	name := strings.ToUpper(strings.Replace(key, ".", "_", -1))
+	if envPrefix != "" {
+		name = strings.ToUpper(envPrefix) + "_" + name
+	}
+	return name
+}
+
+// format renders a value as a string an Enforcer reads back to the same
+// value: lists of strings space separated and maps as JSON.
+func format(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case []interface{}, []string:
+		list, err := cast.ToStringSliceE(v)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(list, " "), nil
+	case map[string]interface{}, map[string]string:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return cast.ToStringE(val)
+}
+
+// YAML renders the ConfigMap and Secret as a multi-document manifest.
+func (b *Bundle) YAML() ([]byte, error) {
+	var docs [][]byte
+	for _, o := range []*Object{b.ConfigMap, b.Secret} {
+		if o == nil {
+			continue
+		}
+		doc, err := yaml.Marshal(o)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return bytes.Join(docs, []byte("---\n")), nil
+}
+
+// EnvYAML renders the env entries for a container spec.
+func (b *Bundle) EnvYAML() ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}{"env": b.Env})
+}
+
+// EnvFromYAML renders the envFrom entries for a container spec.
+func (b *Bundle) EnvFromYAML() ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}{"envFrom": b.EnvFrom})
+}
+
+// Decode returns the values held by the ConfigMaps and Secrets in a
+// manifest, decoding Secret data. Other kinds of object are skipped.
+func Decode(manifest []byte) (map[string]string, error) {
+	vals := map[string]string{}
+	dec := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var o Object
+		err := dec.Decode(&o)
+		if err == io.EOF {
+			return vals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch o.Kind {
+		case "ConfigMap":
+			for k, v := range o.Data {
+				vals[k] = v
+			}
+		case "Secret":
+			for k, v := range o.Data {
+				b, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return nil, errors.New("secret " + o.Metadata.Name + " key " + k + ": " + err.Error())
+				}
+				vals[k] = string(b)
+			}
+			for k, v := range o.StringData {
+				vals[k] = v
+			}
+		}
+	}
+}
+
+// ReadDir returns the values of a ConfigMap or Secret mounted as a volume:
+// one file per key. The hidden files and directories Kubernetes uses to
+// swap the contents atomically are skipped.
+func ReadDir(dir string) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]string{}
+	for _, fi := range entries {
+		if strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		// Keys are symlinks into the current ..data directory.
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		vals[fi.Name()] = string(b)
+	}
+	return vals, nil
+}
@@ -0,0 +1,141 @@
+package kube
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderRoundTrip(t *testing.T) {
+	vals := map[string]interface{}{
+		"db.host":     "db.internal",
+		"db.port":     5432,
+		"db.password": "hunter2",
+		"hosts":       []interface{}{"a", "b"},
+		"labels":      map[string]interface{}{"team": "core"},
+		"unset":       nil,
+	}
+	b, err := Render("app", "prod", "app", vals, func(k string) bool { return k == "db.password" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Secret == nil || b.Secret.Data["db.password"] != "aHVudGVyMg==" {
+		t.Fatalf("password not in the secret, base64 encoded: %+v", b.Secret)
+	}
+	if _, ok := b.ConfigMap.Data["db.password"]; ok {
+		t.Fatal("secret value leaked into the ConfigMap")
+	}
+	if _, ok := b.ConfigMap.Data["unset"]; ok {
+		t.Fatal("nil value rendered")
+	}
+	if b.ConfigMap.Metadata.Namespace != "prod" {
+		t.Fatalf("namespace is %q", b.ConfigMap.Metadata.Namespace)
+	}
+
+	manifest, err := b.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"db.host":     "db.internal",
+		"db.port":     "5432",
+		"db.password": "hunter2",
+		"hosts":       "a b",
+		"labels":      `{"team":"core"}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded %v, want %v", got, want)
+	}
+}
+
+func TestRenderEnv(t *testing.T) {
+	b, err := Render("app", "", "app", map[string]interface{}{"db.host": "h", "token": "t"}, func(k string) bool { return k == "token" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Env) != 2 {
+		t.Fatalf("got %d env entries, want 2", len(b.Env))
+	}
+	host, token := b.Env[0], b.Env[1]
+	if host.Name != "APP_DB_HOST" || host.ValueFrom.ConfigMapKeyRef == nil || host.ValueFrom.ConfigMapKeyRef.Key != "db.host" {
+		t.Errorf("unexpected env entry %+v", host)
+	}
+	if token.Name != "APP_TOKEN" || token.ValueFrom.SecretKeyRef == nil || token.ValueFrom.SecretKeyRef.Name != "app" {
+		t.Errorf("unexpected env entry %+v", token)
+	}
+	if len(b.EnvFrom) != 2 || b.EnvFrom[0].ConfigMapRef == nil || b.EnvFrom[1].SecretRef == nil {
+		t.Errorf("unexpected envFrom %+v", b.EnvFrom)
+	}
+	env, err := b.EnvYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), "secretKeyRef:") {
+		t.Errorf("env YAML has no secretKeyRef:\n%s", env)
+	}
+	if EnvName("", "a.b") != "A_B" {
+		t.Errorf("EnvName without a prefix is %q", EnvName("", "a.b"))
+	}
+}
+
+func TestDecodeStringDataAndOtherKinds(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+stringData:
+  token: plain
+`
+	got, err := Decode([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"token": "plain"}) {
+		t.Fatalf("decoded %v", got)
+	}
+	bad := "kind: Secret\nmetadata:\n  name: app\ndata:\n  token: '!!'\n"
+	if _, err := Decode([]byte(bad)); err == nil {
+		t.Fatal("expected an error for data that is not base64")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	// Lay the directory out the way the kubelet mounts a ConfigMap.
+	data := filepath.Join(dir, "..2019_01_01")
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(data, "db.host"), []byte("db.internal"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "db.host"), filepath.Join(dir, "db.host")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"db.host": "db.internal"}) {
+		t.Fatalf("read %v", got)
+	}
+}
@@ -0,0 +1,56 @@
+package require
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultLogger is used by Enforcers without a Logger. It prints warnings
+// meant for the user, such as a deprecated key or a config file rewritten
+// without its comments, to standard error and drops everything else.
+var defaultLogger = warnings(zapcore.Lock(os.Stderr))
+
+// warnings returns a logger writing warnings and errors to w as plain
+// lines.
+func warnings(w zapcore.WriteSyncer) *zap.Logger {
+	enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		LevelKey:       "level",
+		MessageKey:     "msg",
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+	return zap.New(zapcore.NewCore(enc, w, zap.WarnLevel))
+}
+
+// WithLogger makes the Enforcer and its Decider log how values are
+// resolved to l: files found and loaded, environment variables used,
+// prompts, validation failures and values changed by reloads. Secret values
+// are redacted. Debug level traces every step. Without a logger, warnings
+// are printed to standard error.
+func WithLogger(l *zap.Logger) Initializer {
+	return func(e *Enforcer) {
+		e.Logger = l
+	}
+}
+
+func (e *Enforcer) log() *zap.Logger {
+	if e.Logger == nil {
+		return defaultLogger
+	}
+	return e.Logger
+}
+
+// value is a log field holding val, redacted if key is secret. e.mu must be
+// held.
+func (e *Enforcer) value(name, key string, val interface{}) zap.Field {
+	return zap.Any(name, e.mask(key, val))
+}
+
+// logChanges logs every change in d. e.mu must be held.
+func (e *Enforcer) logChanges(msg string, d Diff) {
+	for _, c := range d.All() {
+		e.log().Info(msg, zap.String("key", c.Key), e.value("old", c.Key, c.Old), e.value("new", c.Key, c.New))
+	}
+}
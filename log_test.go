@@ -0,0 +1,30 @@
+package require
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWarningsShownWithoutLogger(t *testing.T) {
+	var buf bytes.Buffer
+	old := defaultLogger
+	defaultLogger = warnings(zapcore.AddSync(&buf))
+	t.Cleanup(func() { defaultLogger = old })
+
+	dir := writeFiles(t, map[string]string{"app.yaml": "hostname: a\n"})
+	e := NewEnforcer(WithConfigFile(filepath.Join(dir, "app.yaml")))
+	e.Deprecate("hostname", "host")
+	e.Requirements = []string{"host"}
+	initContext(t, e)
+	out := buf.String()
+	if !strings.Contains(out, "WARN") || !strings.Contains(out, "config key is deprecated") || !strings.Contains(out, "hostname") {
+		t.Errorf("deprecation warning not shown, got %q", out)
+	}
+	if strings.Contains(out, "DEBUG") || strings.Contains(out, "INFO") {
+		t.Errorf("default logger shows more than warnings: %q", out)
+	}
+}
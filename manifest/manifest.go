@@ -0,0 +1,173 @@
+// Package manifest describes the config values a program requires in a
+// file that tools can read: to generate typed accessors, to document
+// config or to register requirements on an Enforcer.
+package manifest
+
+import (
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/internal/csvlist"
+	"gopkg.in/yaml.v2"
+)
+
+// Types are the value types a requirement can have, named as in Go.
+var Types = []string{"string", "bool", "int", "int64", "float64", "duration", "[]string", "map[string]string"}
+
+// Requirement is one config value. Type is one of Types and defaults to
+// string. Default is given as it would be on the command line, and Env
+// names an environment variable that supplies the value. Secret values are
+// always required.
+type Requirement struct {
+	Key      string `yaml:"key" json:"key"`
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Usage    string `yaml:"usage,omitempty" json:"usage,omitempty"`
+	Default  string `yaml:"default,omitempty" json:"default,omitempty"`
+	Env      string `yaml:"env,omitempty" json:"env,omitempty"`
+	Secret   bool   `yaml:"secret,omitempty" json:"secret,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// Manifest lists the requirements of a program. A manifest is written in
+// YAML, or JSON, as:
+//
+//	requirements:
+//	- key: db.host
+//	  type: string
+//	  usage: host of the database server
+//	  required: true
+//	- key: db.timeout
+//	  type: duration
+//	  default: 5s
+type Manifest struct {
+	Requirements []Requirement `yaml:"requirements" json:"requirements"`
+}
+
+// Load reads and validates the manifest at path.
+func Load(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(b)
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a manifest.
+func Parse(b []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(b, m); err != nil {
+		return nil, err
+	}
+	return m, m.Validate()
+}
+
+// Validate checks that every requirement has a unique key and a known
+// type, defaulting missing types to string.
+func (m *Manifest) Validate() error {
+	seen := map[string]bool{}
+	for i := range m.Requirements {
+		r := &m.Requirements[i]
+		if r.Key == "" {
+			return errors.New("requirement without a key")
+		}
+		key := strings.ToLower(r.Key)
+		if seen[key] {
+			return errors.New("requirement " + r.Key + " is listed twice")
+		}
+		seen[key] = true
+		if r.Type == "" {
+			r.Type = "string"
+		}
+		if !knownType(r.Type) {
+			return errors.New("requirement " + r.Key + " has unknown type " + r.Type + ", want one of " + strings.Join(Types, ", "))
+		}
+	}
+	return nil
+}
+
+// Marshal renders m as YAML.
+func (m *Manifest) Marshal() ([]byte, error) {
+	return yaml.Marshal(m)
+}
+
+func knownType(typ string) bool {
+	for _, t := range Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Sample returns a config holding every requirement at its default, or the
+// zero value of its type when it has none, nested by key. Secrets are left
+// empty.
+func (m *Manifest) Sample() (map[string]interface{}, error) {
+	vals := map[string]interface{}{}
+	for _, r := range m.Requirements {
+		val, err := sampleValue(r)
+		if err != nil {
+			return nil, errors.New("default of " + r.Key + ": " + err.Error())
+		}
+		helm.SetPath(vals, r.Key, val)
+	}
+	return vals, nil
+}
+
+func sampleValue(r Requirement) (interface{}, error) {
+	def := r.Default
+	if r.Secret {
+		def = ""
+	}
+	switch r.Type {
+	case "bool":
+		if def == "" {
+			return false, nil
+		}
+		return strconv.ParseBool(def)
+	case "int", "int64":
+		if def == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(def, 10, 64)
+	case "float64":
+		if def == "" {
+			return 0.0, nil
+		}
+		return strconv.ParseFloat(def, 64)
+	case "duration":
+		if def == "" {
+			return "0s", nil
+		}
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return nil, err
+		}
+		return d.String(), nil
+	case "[]string":
+		return csvlist.Parse(def)
+	case "map[string]string":
+		m := map[string]interface{}{}
+		pairs, err := csvlist.Parse(def)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.New("expected key=value, got " + pair)
+			}
+			m[kv[0]] = kv[1]
+		}
+		return m, nil
+	}
+	return def, nil
+}
@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `requirements:
+- key: db.host
+  usage: host of the database server
+  required: true
+- key: db.timeout
+  type: duration
+  default: 90s
+- key: db.password
+  secret: true
+  default: hunter2
+- key: replicas
+  type: int
+  default: "3"
+- key: debug
+  type: bool
+- key: hosts
+  type: "[]string"
+  default: a,"b,c"
+- key: labels
+  type: map[string]string
+  default: team=core,tier=db
+`
+
+func TestParseAndSample(t *testing.T) {
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Requirements[0].Type != "string" {
+		t.Errorf("missing type defaulted to %q, want string", m.Requirements[0].Type)
+	}
+	vals, err := m.Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":     "",
+			"timeout":  "1m30s",
+			"password": "",
+		},
+		"replicas": int64(3),
+		"debug":    false,
+		"hosts":    []string{"a", "b,c"},
+		"labels":   map[string]interface{}{"team": "core", "tier": "db"},
+	}
+	if !reflect.DeepEqual(vals, want) {
+		t.Fatalf("sample is %#v, want %#v", vals, want)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := map[string]string{
+		"requirements:\n- usage: no key\n":                   "without a key",
+		"requirements:\n- key: a\n- key: A\n":                "listed twice",
+		"requirements:\n- key: a\n  type: uint8\n":           "unknown type",
+		"requirements:\n- key: a\n  kind: string\n":          "kind",
+		"requirements:\n- key: a\n  type: int\n  default: x": "",
+	}
+	for doc, want := range tests {
+		m, err := Parse([]byte(doc))
+		if want == "" {
+			// Valid, but the default does not parse as its type.
+			if err != nil {
+				t.Errorf("%q: %v", doc, err)
+				continue
+			}
+			if _, err := m.Sample(); err == nil {
+				t.Errorf("%q: expected Sample to reject the default", doc)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want one mentioning %q", doc, err, want)
+		}
+	}
+}
+
+func TestLoadRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "require.yaml")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Fatalf("loaded %+v, want %+v", loaded, m)
+	}
+	if err := ioutil.WriteFile(path, []byte("requirements:\n- key: a\n  type: nope\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.HasPrefix(err.Error(), path+": ") {
+		t.Fatalf("expected an error naming the file, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package require
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gofunct/require/audit"
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/source"
+	"github.com/gofunct/require/yamledit"
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// VersionKey is the config key holding the version of the config format,
+// which decides the migrations Migrate applies.
+const VersionKey = "config_version"
+
+// Migration is one step in the history of a config format. Config files
+// whose config_version is older than Version are upgraded by renaming the
+// keys in Renames, old name to new, and then calling Apply.
+type Migration struct {
+	Version string
+	Renames map[string]string
+	// Apply makes any change renames cannot express. It receives the whole
+	// config as nested maps. It is only called by Migrate.
+	Apply func(vals map[string]interface{}) error
+}
+
+type rename struct {
+	from, to   string
+	deprecated bool
+}
+
+// Alias lets alias be used in config files in place of key.
+func (e *Enforcer) Alias(alias, key string) {
+	e.mu.Lock()
+	e.renames = append(e.renames, rename{from: strings.ToLower(alias), to: strings.ToLower(key)})
+	e.mu.Unlock()
+}
+
+// Deprecate marks old as a deprecated name for key. A config that still
+// sets old satisfies key, with a warning naming the replacement, and
+// Migrate rewrites it to key.
+func (e *Enforcer) Deprecate(old, key string) {
+	e.mu.Lock()
+	e.renames = append(e.renames, rename{from: strings.ToLower(old), to: strings.ToLower(key), deprecated: true})
+	e.mu.Unlock()
+}
+
+// AddMigration registers a versioned migration step.
+func (e *Enforcer) AddMigration(m Migration) error {
+	if _, err := version.NewVersion(m.Version); err != nil {
+		return errors.New("invalid migration version " + m.Version + ": " + err.Error())
+	}
+	e.mu.Lock()
+	e.migrations = append(e.migrations, m)
+	e.mu.Unlock()
+	return nil
+}
+
+// Migrate upgrades config files in place: deprecated keys are renamed and
+// every migration newer than the file's config_version is applied in order,
+// after which config_version is set to the newest one. With no files it
+// migrates the config file in use and reloads it.
+func (e *Enforcer) Migrate(files ...string) error {
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	reload := len(files) == 0
+	if reload {
+		if e.v.ConfigFileUsed() == "" {
+			return errors.New("no config file was loaded to migrate")
+		}
+		files = []string{e.v.ConfigFileUsed()}
+	}
+	var current []byte
+	for _, file := range files {
+		vals, err := source.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		old := helm.Leaves(vals)
+		from := cast.ToString(vals[VersionKey])
+		steps, err := e.pendingMigrations(from)
+		if err != nil {
+			return errors.New("cannot migrate " + file + ": " + err.Error())
+		}
+		for _, r := range e.renames {
+			if r.deprecated {
+				moveKey(vals, r.from, r.to)
+			}
+		}
+		for _, m := range steps {
+			for from, to := range m.Renames {
+				moveKey(vals, strings.ToLower(from), strings.ToLower(to))
+			}
+			if m.Apply != nil {
+				if err := m.Apply(vals); err != nil {
+					return errors.New("migration to " + m.Version + " failed for " + file + ": " + err.Error())
+				}
+			}
+			vals[VersionKey] = m.Version
+		}
+		b, err := e.encodeMigrated(file, old, vals)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, b, info.Mode()); err != nil {
+			return err
+		}
+		current = b
+		if e.Audit != nil && len(steps) > 0 {
+			ev := audit.NewEvent(VersionKey, from, vals[VersionKey], "migrate "+file, false)
+			if err := e.Audit.Record(ev); err != nil {
+				e.log().Error("audit record failed", zap.String("key", ev.Key), zap.Error(err))
+			}
+		}
+		e.log().Info("config file migrated", zap.String("file", file), zap.String("from", from), zap.Int("steps", len(steps)))
+	}
+	if !reload {
+		return nil
+	}
+	before := settings(e.v)
+	if err := e.loadConfig(current); err != nil {
+		return err
+	}
+	if err := e.resolve(); err != nil {
+		return err
+	}
+	e.commit(before)
+	return nil
+}
+
+// encodeMigrated renders vals, the migrated values of file, whose keys
+// were old. A YAML file is edited in place, deleting the keys the migration
+// removed, so its comments and layout survive as they do in UpdateConfigs.
+func (e *Enforcer) encodeMigrated(file string, old []string, vals map[string]interface{}) ([]byte, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
+	if ext == "yaml" || ext == "yml" {
+		b, err := editMigrated(file, old, vals)
+		if err == nil {
+			return b, nil
+		}
+		e.log().Warn("config file rewritten without its comments", zap.String("file", file), zap.Error(err))
+	}
+	return source.Encode(vals, ext)
+}
+
+func editMigrated(file string, old []string, vals map[string]interface{}) ([]byte, error) {
+	doc, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range old {
+		if _, ok := helm.Lookup(vals, k); ok {
+			continue
+		}
+		if doc, err = yamledit.Delete(doc, strings.Split(k, ".")); err != nil {
+			return nil, err
+		}
+	}
+	return yamledit.Apply(doc, vals)
+}
+
+// pendingMigrations returns the migrations newer than version from, oldest
+// first. An empty from means every migration is pending.
+func (e *Enforcer) pendingMigrations(from string) ([]Migration, error) {
+	var cur *version.Version
+	if from != "" {
+		v, err := version.NewVersion(from)
+		if err != nil {
+			return nil, errors.New("invalid " + VersionKey + " " + from + ": " + err.Error())
+		}
+		cur = v
+	}
+	var steps []Migration
+	for _, m := range e.migrations {
+		v := version.Must(version.NewVersion(m.Version))
+		if cur == nil || v.GreaterThan(cur) {
+			steps = append(steps, m)
+		}
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		return version.Must(version.NewVersion(steps[i].Version)).LessThan(version.Must(version.NewVersion(steps[j].Version)))
+	})
+	return steps, nil
+}
+
+// applyRenames makes values set under aliases, deprecated names and the
+// old names of pending migrations satisfy the keys that replaced them,
+// warning about every name that should be migrated. e.mu must be held.
+func (e *Enforcer) applyRenames() error {
+	var renames []rename
+	if len(e.migrations) > 0 {
+		steps, err := e.pendingMigrations(cast.ToString(e.v.Get(VersionKey)))
+		if err != nil {
+			return err
+		}
+		for _, m := range steps {
+			for from, to := range m.Renames {
+				renames = append(renames, rename{from: strings.ToLower(from), to: strings.ToLower(to), deprecated: true})
+			}
+		}
+	}
+	renames = append(renames, e.renames...)
+	moved := map[string]interface{}{}
+	for _, r := range renames {
+		if !e.v.IsSet(r.from) || e.v.IsSet(r.to) {
+			continue
+		}
+		helm.SetPath(moved, r.to, e.v.Get(r.from))
+		if r.deprecated && !e.warned[r.from] {
+			if e.warned == nil {
+				e.warned = map[string]bool{}
+			}
+			e.warned[r.from] = true
+			e.log().Warn("config key is deprecated", zap.String("key", r.from), zap.String("replacement", r.to))
+		}
+		if src, ok := e.origins[r.from]; ok {
+			e.setSource(r.to, src)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	return e.v.MergeConfigMap(moved)
+}
+
+// resolveRenames is applyRenames for Init.
+func (e *Enforcer) resolveRenames() error {
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	before := settings(e.v)
+	if err := e.applyRenames(); err != nil {
+		return err
+	}
+	e.commit(before)
+	return nil
+}
+
+// moveKey moves the value at path from to path to unless to is already
+// set.
+func moveKey(vals map[string]interface{}, from, to string) {
+	val, ok := helm.Lookup(vals, from)
+	if !ok {
+		return
+	}
+	if _, taken := helm.Lookup(vals, to); !taken {
+		helm.SetPath(vals, to, val)
+	}
+	helm.DeletePath(vals, from)
+}
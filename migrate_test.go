@@ -0,0 +1,32 @@
+package require
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateEachFileFromItsOwnVersion(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"new.yaml": "config_version: \"1.0\"\nb: 1 # kept\n",
+		"old.yaml": "# the app config\na: 1 # kept\nname: app\n",
+	})
+	e := NewEnforcer()
+	for _, m := range []Migration{
+		{Version: "1.0", Renames: map[string]string{"a": "b"}},
+		{Version: "2.0", Renames: map[string]string{"b": "c"}},
+	} {
+		if err := e.AddMigration(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newFile, oldFile := filepath.Join(dir, "new.yaml"), filepath.Join(dir, "old.yaml")
+	if err := e.Migrate(newFile, oldFile); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, newFile), "config_version: \"2.0\"\nc: 1\n"; got != want {
+		t.Errorf("new.yaml =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := readFile(t, oldFile), "# the app config\nname: app\nc: 1\nconfig_version: \"2.0\"\n"; got != want {
+		t.Errorf("old.yaml =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,91 +1,305 @@
 package option
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/codegangsta/cli"
+	"github.com/gofunct/require/internal/csvlist"
 	"github.com/spf13/viper"
 	"gopkg.in/dixonwille/wmenu.v4"
-	"time"
 )
 
 type API interface {
 	viper.FlagValue
 }
 
+// Option is the single definition of a config value behind its command
+// line flag, environment variable and prompt. Pointer is the typed
+// destination the value is parsed into: *bool, *string, *int, *int64,
+// *float64, *time.Duration, *[]string or *map[string]string.
 type Option struct {
-	Pointer interface{}
-	ID int
-	Key string
-	Env string
-	Usage string
+	Pointer  interface{}
+	ID       int
+	Key      string
+	Env      string
+	Usage    string
 	Required bool
-	Default string
-	menu *wmenu.Opt
-	clif cli.Flag
+	Default  string
+	menu     *wmenu.Opt
+	clif     cli.Flag
+	changed  bool
 }
 
+// HasChanged reports whether the value was set from a flag, the environment
+// or Set since the option was created.
 func (o *Option) HasChanged() bool {
-	panic("implement me")
+	return o.changed
 }
 
 func (o *Option) Name() string {
 	return o.Key
 }
 
+// ValueString formats the current value the way viper expects for the
+// option's ValueType.
 func (o *Option) ValueString() string {
-	return o.clif.String()
+	switch p := o.Pointer.(type) {
+	case *[]string:
+		return "[" + writeCSV(*p) + "]"
+	case *map[string]string:
+		b, _ := json.Marshal(*p)
+		return string(b)
+	}
+	return o.String()
 }
 
+// ValueType names the type of the option as pflag does, which decides how
+// viper converts ValueString.
 func (o *Option) ValueType() string {
+	switch o.Pointer.(type) {
+	case *bool:
+		return "bool"
+	case *string:
+		return "string"
+	case *int:
+		return "int"
+	case *int64:
+		return "int64"
+	case *float64:
+		return "float64"
+	case *time.Duration:
+		return "duration"
+	case *[]string:
+		return "stringSlice"
+	case *map[string]string:
+		return "stringToString"
+	}
 	return fmt.Sprintf("%T", o.Pointer)
 }
 
-func NewOption(dest interface{}, id int, name, env,  def, usage string, required bool) *Option {
-	viper.SetDefault(name, def)
-	viper.BindEnv(name, def)
+// Value returns the current value, dereferenced from Pointer.
+func (o *Option) Value() interface{} {
+	switch p := o.Pointer.(type) {
+	case *bool:
+		return *p
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *int64:
+		return *p
+	case *float64:
+		return *p
+	case *time.Duration:
+		return *p
+	case *[]string:
+		return *p
+	case *map[string]string:
+		return *p
+	}
+	return nil
+}
+
+// String formats the current value as it would be given on the command
+// line.
+func (o *Option) String() string {
+	switch p := o.Pointer.(type) {
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *int64:
+		return strconv.FormatInt(*p, 10)
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return writeCSV(*p)
+	case *map[string]string:
+		keys := make([]string, 0, len(*p))
+		for k := range *p {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + (*p)[k]
+		}
+		return writeCSV(pairs)
+	}
+	return ""
+}
+
+// Set parses s into Pointer and marks the option changed. Lists are comma
+// separated, and maps are comma separated key=value pairs.
+func (o *Option) Set(s string) error {
+	if err := o.parse(s); err != nil {
+		return errors.New("invalid value " + strconv.Quote(s) + " for " + o.Key + ": " + err.Error())
+	}
+	o.changed = true
+	return nil
+}
+
+// IsBoolFlag lets boolean options be given as a bare --flag.
+func (o *Option) IsBoolFlag() bool {
+	_, ok := o.Pointer.(*bool)
+	return ok
+}
+
+// Flag returns the command line flag for the option.
+func (o *Option) Flag() cli.Flag {
+	return o.clif
+}
+
+// Bind registers the option with v: its default, its environment variable
+// and the option itself as the flag behind Key.
+func (o *Option) Bind(v *viper.Viper) error {
+	v.SetDefault(o.Key, o.Value())
+	if o.Env != "" {
+		if err := v.BindEnv(o.Key, o.Env); err != nil {
+			return err
+		}
+	}
+	return v.BindFlagValue(o.Key, o)
+}
+
+func (o *Option) parse(s string) error {
+	switch p := o.Pointer.(type) {
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case *string:
+		*p = s
+	case *int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = i
+	case *int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*p = i
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*p = f
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case *[]string:
+		list, err := csvlist.Parse(s)
+		if err != nil {
+			return err
+		}
+		*p = list
+	case *map[string]string:
+		pairs, err := csvlist.Parse(s)
+		if err != nil {
+			return err
+		}
+		m := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return errors.New("expected key=value, got " + pair)
+			}
+			m[strings.TrimSpace(kv[0])] = kv[1]
+		}
+		*p = m
+	default:
+		return fmt.Errorf("unsupported option type %T", o.Pointer)
+	}
+	return nil
+}
+
+func writeCSV(list []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(list)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Flags returns the command line flags for opts, in order.
+func Flags(opts ...*Option) []cli.Flag {
+	flags := make([]cli.Flag, len(opts))
+	for i, o := range opts {
+		flags[i] = o.Flag()
+	}
+	return flags
+}
+
+// NewOption defines an option parsed into dest, with def as its default
+// value in command line form, and binds it to the global viper instance.
+// Libraries should define options in a Set instead, so they cannot clash
+// with each other's.
+// It panics if dest is not a supported type or def does not parse, since
+// both are mistakes in the program rather than in its input.
+func NewOption(dest interface{}, id int, name, env, def, usage string, required bool) *Option {
+	o, err := New(dest, id, name, env, def, usage, required)
+	if err != nil {
+		panic(err)
+	}
+	if err := o.Bind(viper.GetViper()); err != nil {
+		panic("option " + name + ": " + err.Error())
+	}
+	return o
+}
+
+// New defines an option like NewOption without binding it to any viper
+// instance.
+func New(dest interface{}, id int, name, env, def, usage string, required bool) (*Option, error) {
 	o := &Option{
+		Pointer:  dest,
 		ID:       id,
-		Key:     name,
+		Key:      name,
 		Env:      env,
 		Usage:    usage,
 		Required: required,
-		Default: def,
-		menu:     &wmenu.Opt{
+		Default:  def,
+		menu: &wmenu.Opt{
 			ID:    id,
 			Text:  usage,
 			Value: dest,
 		},
 	}
 	switch dest.(type) {
-	case (*bool):
-		o.clif = &cli.BoolFlag{
-			Name:        name,
-			Usage:       usage,
-			EnvVar:      env,
-			Destination: dest.(*bool),
-		}
-	case (*string):
-		o.clif = &cli.StringFlag{
-			Name:        name,
-			Usage:       usage,
-			EnvVar:      env,
-			Value:       def,
-			Destination: dest.(*string),
-		}
-	case (time.Duration):
-		o.clif = &cli.DurationFlag{
-			Name:   name,
-			Usage:  usage,
-			EnvVar: env,
-			Value: dest.(time.Duration),
-		}
-	case (*int):
-		o.clif = &cli.IntFlag{
-			Name:   name,
-			Usage:  usage,
-			EnvVar: env,
-			Value: dest.(int),
+	case *bool, *string, *int, *int64, *float64, *time.Duration, *[]string, *map[string]string:
+	default:
+		return nil, fmt.Errorf("option %s: unsupported option type %T", name, dest)
+	}
+	if def != "" {
+		if err := o.parse(def); err != nil {
+			return nil, errors.New("option " + name + ": " + err.Error())
 		}
 	}
-	return o
+	o.clif = cli.GenericFlag{
+		Name:   name,
+		Usage:  usage,
+		EnvVar: env,
+		Value:  o,
+	}
+	return o, nil
 }
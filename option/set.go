@@ -0,0 +1,127 @@
+package option
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/codegangsta/cli"
+	"github.com/spf13/viper"
+)
+
+// Set is a registry of options that several subsystems can contribute to
+// before they are bound together into one command and one viper instance.
+// Unlike NewOption it never touches the global viper.
+type Set struct {
+	mu   sync.Mutex
+	opts []*Option
+	keys map[string]*Option
+	envs map[string]*Option
+}
+
+func NewSet() *Set {
+	return &Set{
+		keys: map[string]*Option{},
+		envs: map[string]*Option{},
+	}
+}
+
+// Define creates an option with New and adds it to the set.
+func (s *Set) Define(dest interface{}, name, env, def, usage string, required bool) (*Option, error) {
+	s.mu.Lock()
+	id := len(s.opts)
+	s.mu.Unlock()
+	o, err := New(dest, id, name, env, def, usage, required)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Add(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Add registers opts, failing without adding any of them if one reuses the
+// key or environment variable of an option already in the set or earlier
+// in opts.
+func (s *Set) Add(opts ...*Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = map[string]*Option{}
+		s.envs = map[string]*Option{}
+	}
+	keys := map[string]*Option{}
+	envs := map[string]*Option{}
+	for _, o := range opts {
+		key := strings.ToLower(o.Key)
+		if prev, ok := s.keys[key]; ok && prev != o {
+			return errors.New("option " + o.Key + " is already defined")
+		}
+		if _, ok := keys[key]; ok {
+			return errors.New("option " + o.Key + " is defined twice")
+		}
+		keys[key] = o
+		if o.Env == "" {
+			continue
+		}
+		if prev, ok := s.envs[o.Env]; ok && prev != o {
+			return errors.New("option " + o.Key + " uses $" + o.Env + ", which is already bound to " + prev.Key)
+		}
+		if prev, ok := envs[o.Env]; ok {
+			return errors.New("option " + o.Key + " uses $" + o.Env + ", which is already bound to " + prev.Key)
+		}
+		envs[o.Env] = o
+	}
+	for _, o := range opts {
+		key := strings.ToLower(o.Key)
+		if _, ok := s.keys[key]; ok {
+			continue
+		}
+		s.keys[key] = o
+		if o.Env != "" {
+			s.envs[o.Env] = o
+		}
+		s.opts = append(s.opts, o)
+	}
+	return nil
+}
+
+// Include adds the options of other sets.
+func (s *Set) Include(sets ...*Set) error {
+	for _, other := range sets {
+		if err := s.Add(other.Options()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the option for key, or nil.
+func (s *Set) Lookup(key string) *Option {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[strings.ToLower(key)]
+}
+
+// Options returns the options in the order they were added.
+func (s *Set) Options() []*Option {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Option(nil), s.opts...)
+}
+
+// Flags returns the command line flags for every option in the set.
+func (s *Set) Flags() []cli.Flag {
+	return Flags(s.Options()...)
+}
+
+// Bind binds every option in the set to v.
+func (s *Set) Bind(v *viper.Viper) error {
+	for _, o := range s.Options() {
+		if err := o.Bind(v); err != nil {
+			return errors.New("option " + o.Key + ": " + err.Error())
+		}
+	}
+	return nil
+}
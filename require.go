@@ -1,26 +1,104 @@
 package require
 
 import (
+	"context"
 	"errors"
 	"flag"
-	"github.com/gofunct/require/api"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofunct/require/audit"
 	"github.com/gofunct/require/decider"
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/option"
+	"github.com/gofunct/require/secret"
+	"github.com/gofunct/require/source"
+	"github.com/gofunct/require/yamledit"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/tcnksm/go-input"
-	"os"
+	"go.uber.org/zap"
 )
 
+// Value is the key of a configuration value an Enforcer requires.
+type Value = string
+
 type Initializer func(e *Enforcer)
 
 type Enforcer struct {
-	Name 				string
-	Paths 				[]string
-	Ext 				string
-	EnvPrefix 			string
-	Requirements 		[]Value
-	dcdr 				*decider.Decider
-	v 					*viper.Viper
+	Name  string
+	Paths []string
+	// Ext is the format looked for first and the one a config file is
+	// created in when none exists. A file in any format in source.Exts is
+	// found, and written back in its own format.
+	Ext          string
+	EnvPrefix    string
+	Requirements []Value
+	// Sources are read by Init and merged over the local config file.
+	// Entries in Paths that are go-getter URLs are added as Getter sources
+	// caching into CacheDir.
+	Sources  []source.Source
+	CacheDir string
+	// Secrets resolves secret:// references and secret requirements.
+	// Results are cached for SecretTTL when it is set.
+	Secrets   secret.Provider
+	SecretTTL time.Duration
+	// KeyFile holds the key ENC[...] values are decrypted with when
+	// $REQUIRE_SECRET_KEY and $REQUIRE_SECRET_KEY_FILE are not set.
+	KeyFile string
+	// PromptTimeout bounds how long a single prompt waits for an answer
+	// before falling back to its default, or failing when FailOnTimeout is
+	// set or there is no default. Zero waits indefinitely.
+	PromptTimeout time.Duration
+	FailOnTimeout bool
+	// CommandTimeout bounds how long a RequireCommand default may take to
+	// compute. It defaults to ten seconds.
+	CommandTimeout time.Duration
+	// Logger receives structured events about how values are resolved. See
+	// WithLogger.
+	Logger *zap.Logger
+	// Audit, when set, records every value set by a prompt or by Set and
+	// every config file Migrate upgrades, with who made the change and from
+	// which command.
+	Audit   audit.Sink
+	dcdr    *decider.Decider
+	v       *viper.Viper
+	chart   *helm.Chart
+	origins map[string]string
+	overlay *helm.Overlay
+	// base holds the values read from the config file, as it has them, and
+	// overlaid the result of applying an overlay to them.
+	base     map[string]interface{}
+	overlaid map[string]interface{}
+	remote   map[string]interface{}
+	read     []map[string]interface{}
+	secret   map[string]bool
+	// resolved holds the secrets resolveSecrets filled in, so the
+	// reference or ciphertext they were read as is written back instead.
+	resolved   map[string]resolution
+	expanded   map[string]expansion
+	commands   map[string]string
+	computed   map[string]string
+	renames    []rename
+	migrations []Migration
+	warned     map[string]bool
+	options    *option.Set
+	sealKey    *secret.Key
+	subs       subscriptions
+	// mu guards v and everything derived from it. Readers use snap, a copy
+	// of the resolved settings replaced after every write, so they never
+	// wait on a reload or prompt.
+	mu     sync.Mutex
+	snap   atomic.Value
+	prompt sync.Mutex
 }
 
 func NewEnforcer(inits ...Initializer) *Enforcer {
@@ -34,80 +112,204 @@ func NewEnforcer(inits ...Initializer) *Enforcer {
 	if len(e.Paths) == 0 {
 		e.Paths = []string{".", os.Getenv("HOME"), "..", os.Getenv("REQUIRE_PATH")}
 	}
-	if e.v == nil {
-		e.v = viper.New()
-		_ = e.v.MergeInConfig()
+	if e.Ext == "" {
+		e.Ext = "yaml"
+	}
+	if e.CacheDir == "" {
+		e.CacheDir = defaultCacheDir()
+	}
+	if e.CommandTimeout == 0 {
+		e.CommandTimeout = 10 * time.Second
+	}
+	if e.Secrets != nil && e.SecretTTL > 0 {
+		e.Secrets = secret.NewCache(e.Secrets, e.SecretTTL)
 	}
 	if e.dcdr == nil {
-		e.dcdr = decider.NewDecider()
+		e.dcdr = decider.NewDecider(e.Name)
 	}
-	if e.Ext == "" {
-		e.Ext = "yaml"
+	if e.v == nil {
+		e.v = viper.New()
+		e.configure()
+		e.mergeInConfig()
 	}
+	e.snap.Store(settings(e.v))
 	return e
 }
 
-func(v Value) Type() {
-	switch v.(type) {
-
+// WithConfigFile makes an Enforcer read path as its config file, taking its
+// name and format from the file name and searching only its directory.
+func WithConfigFile(path string) Initializer {
+	return func(e *Enforcer) {
+		ext := filepath.Ext(path)
+		e.Name = strings.TrimSuffix(filepath.Base(path), ext)
+		e.Ext = strings.TrimPrefix(ext, ".")
+		e.Paths = []string{filepath.Dir(path)}
 	}
 }
 
 func NewHelmEnforcer(reqs ...Value) *Enforcer {
 	e := &Enforcer{
-		Name:      "values",
-		Paths:     []string{"./helm", "helm", "deploy", "./deploy", os.Getenv("HOME")+"/helm", "../helm", os.Getenv("REQUIRE_HELM_PATH")},
-		Ext:       "yaml",
-		EnvPrefix: "helm",
-		dcdr:     decider.NewDecider(),
-		v:         viper.New(),
-		Requirements: reqs,
+		Name:           "values",
+		Paths:          []string{"./helm", "helm", "deploy", "./deploy", os.Getenv("HOME") + "/helm", "../helm", os.Getenv("REQUIRE_HELM_PATH")},
+		Ext:            "yaml",
+		EnvPrefix:      "helm",
+		dcdr:           decider.NewDecider("values"),
+		v:              viper.New(),
+		Requirements:   reqs,
+		CommandTimeout: 10 * time.Second,
+	}
+	e.configure()
+	if chart, err := helm.FindChart(e.Paths...); err == nil {
+		e.chart = chart
+		e.v.SetConfigFile(chart.ValuesFile())
 	}
+	e.mergeInConfig()
+	e.snap.Store(settings(e.v))
 	return e
 }
 
-func (e *Enforcer)  Init() error {
-	if len(e.Requirements)== 0 {
-		return errors.New("no requirements were found")
+// mergeInConfig loads the config file set on viper, or else the first one
+// findConfig finds, if any.
+func (e *Enforcer) mergeInConfig() {
+	file := e.v.ConfigFileUsed()
+	if file == "" {
+		file = e.findConfig()
+	}
+	if file == "" {
+		e.log().Debug("no config file found", zap.String("name", e.Name), zap.Strings("paths", e.remoteless()))
+		return
+	}
+	e.log().Debug("config file found", zap.String("file", file))
+	e.v.SetConfigFile(file)
+	b, err := ioutil.ReadFile(file)
+	if err == nil {
+		err = e.loadConfig(b)
 	}
-	for _, key := range e.Requirements {
-		if !e.v.IsSet(key) || e.v.Get(key) == "" || e.v.Get(key) == nil {
-			if val, exists := os.LookupEnv(key); val != "" && exists == true {
-				e.v.Set(key, val)
-			} else {
-				typ, err := e.input.Select("Please provide a type for the following key: "+key, []string{"string", "int", "bool", "[]slice"}, &input.Options{
-					Default: "string",
-					Loop:         true,
-					Required:     true,
-					ValidateFunc: e.Ensure(),
-				})
-				ans, err := e.query.Ask("Please provide a value for the following key: "+key, &input.Options{
-					Loop:         true,
-					Required:     true,
-					ValidateFunc: e.Ensure(),
-				})
-				if err != nil {
-					panic(err)
-				}
-				e.v.Set(key, ans)
-				_ = os.Setenv(key, ans)
+	if err != nil {
+		e.log().Warn("config file could not be loaded", zap.String("file", file), zap.Error(err))
+		return
+	}
+	e.log().Info("config file loaded", zap.String("file", file), zap.Int("keys", len(e.v.AllKeys())))
+}
+
+// findConfig returns the first file named after the Enforcer in its local
+// search paths with the extension of a supported format, trying Ext before
+// the others in each directory.
+func (e *Enforcer) findConfig() string {
+	exts := append([]string{e.Ext}, source.Exts...)
+	for _, dir := range e.remoteless() {
+		for _, ext := range exts {
+			file := filepath.Join(dir, e.Name+"."+ext)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file
 			}
 		}
 	}
+	return ""
+}
+
+// format returns the extension naming the format of the config file: the
+// file's own extension, YAML for Helm values, or Ext when there is no file
+// yet.
+func (e *Enforcer) format() string {
+	if e.chart != nil || e.overlay != nil {
+		return "yaml"
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(e.v.ConfigFileUsed()), "."); source.Supported(ext) {
+		return strings.ToLower(ext)
+	}
+	return e.Ext
+}
+
+// configure points the underlying viper instance at the Enforcer's name,
+// search paths and environment prefix.
+func (e *Enforcer) configure() {
+	e.v.SetConfigName(e.Name)
+	for _, p := range e.remoteSources() {
+		if p != "" {
+			e.v.AddConfigPath(p)
+		}
+	}
+	if e.EnvPrefix != "" {
+		e.v.SetEnvPrefix(e.EnvPrefix)
+	}
+	e.v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	e.v.AutomaticEnv()
+}
+
+func (e *Enforcer) Init() error {
+	return e.InitContext(context.Background())
+}
+
+// addRequirements appends keys to Requirements. Reloads read Requirements
+// under e.mu, so the Enforcer's own methods only change it holding e.mu.
+func (e *Enforcer) addRequirements(keys ...string) {
+	e.mu.Lock()
+	e.Requirements = append(e.Requirements, keys...)
+	e.mu.Unlock()
+}
+
+// requirements returns a copy of Requirements.
+func (e *Enforcer) requirements() []Value {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Value(nil), e.Requirements...)
+}
 
+// InitContext is Init that stops prompting and returns ctx.Err() as soon as
+// ctx is done, so a deadline or an interrupt never leaves a process waiting
+// on a terminal.
+func (e *Enforcer) InitContext(ctx context.Context) error {
+	reqs := e.requirements()
+	if len(reqs) == 0 && e.chart == nil {
+		return errors.New("no requirements were found")
+	}
+	if len(e.Sources) > 0 {
+		if err := e.LoadSources(ctx); err != nil {
+			return err
 		}
 	}
-	return nil
+	if err := e.resolveRenames(); err != nil {
+		return err
+	}
+	if err := e.ResolveSecrets(ctx); err != nil {
+		return err
+	}
+	e.prompt.Lock()
+	e.computed = nil
+	e.prompt.Unlock()
+	for _, key := range reqs {
+		if _, err := e.ensureContext(ctx, key); err != nil {
+			e.log().Warn("requirement not satisfied", zap.String("key", key), zap.Error(err))
+			return err
+		}
+	}
+	if e.chart != nil {
+		if err := e.enforceSchema(ctx); err != nil {
+			e.log().Error("validation failed", zap.String("chart", e.chart.Name), zap.Error(err))
+			return err
+		}
+	}
+	return e.Interpolate()
 }
 
 func (e *Enforcer) Sub(key string) *Enforcer {
-	return &Enforcer{
+	e.mu.Lock()
+	v := e.v.Sub(key)
+	e.mu.Unlock()
+	if v == nil {
+		v = viper.New()
+	}
+	sub := &Enforcer{
 		Name:      key,
 		Paths:     e.Paths,
 		EnvPrefix: e.EnvPrefix,
-		dcdr:     decider.NewDecider(),
-		v:      	e.v.Sub(key),
+		dcdr:      decider.NewDecider(key),
+		v:         v,
+		Logger:    e.Logger,
 	}
+	sub.snap.Store(settings(v))
+	return sub
 }
 
 func (e *Enforcer) RequireBool(key string) {
@@ -154,88 +356,221 @@ func (e *Enforcer) BindFlagSet(f *flag.FlagSet) {
 	panic("implement me")
 }
 
+// Debug prints every key the Enforcer knows about along with its value and
+// where that value came from.
 func (e *Enforcer) Debug() {
-	panic("implement me")
+	vals := e.snapshot()
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.mu.Lock()
+		val := e.mask(k, vals[k])
+		e.mu.Unlock()
+		if raw, refs, ok := e.Expansion(k); ok && len(refs) > 0 {
+			fmt.Printf("%s = %v (%s, expanded from %q using %s)\n", k, val, e.Source(k), raw, strings.Join(refs, ", "))
+			continue
+		}
+		fmt.Printf("%s = %v (%s)\n", k, val, e.Source(k))
+	}
 }
 
+// UpdateConfigs writes the current settings back to the config file, and
+// writes the keys each writable source supplied back to that source with
+// their current values.
 func (e *Enforcer) UpdateConfigs() error {
-	panic("implement me")
+	return e.UpdateConfigsContext(context.Background())
 }
 
-func (e *Enforcer) RequireString(key string) {
-	if !e.v.IsSet(key) || e.v.GetString(key) == "" {
-		if val, exists := os.LookupEnv(key); val != "" && exists == true {
-			e.v.Set(key, val)
-		} else {
-			ans, err := e.query.Ask("Please provide a value for the following key: "+key, &input.Options{
-				Loop:         true,
-				Required:     true,
-				ValidateFunc: e.Ensure(),
-			})
-			if err != nil {
-				panic(err)
+// UpdateConfigsContext is UpdateConfigs with a context for the writes to
+// remote sources.
+func (e *Enforcer) UpdateConfigsContext(ctx context.Context) error {
+	e.mu.Lock()
+	file := e.v.ConfigFileUsed()
+	if file == "" {
+		dir := "."
+		if local := e.remoteless(); len(local) > 0 {
+			dir = local[0]
+		}
+		file = filepath.Join(dir, e.Name+"."+e.Ext)
+	}
+	b, err := e.encodeConfig(file)
+	if err == nil {
+		err = ioutil.WriteFile(file, b, 0644)
+	}
+	writes := e.pendingWrites()
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, w := range writes {
+		if err := w.src.Write(ctx, w.vals); err != nil {
+			return errors.New("failed to update " + w.src.Name() + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// encodeConfig renders the current settings for file. A YAML file that
+// already exists is edited in place, so its comments, key order, anchors
+// and quoting survive; any other file is written afresh. e.mu must be held.
+func (e *Enforcer) encodeConfig(file string) ([]byte, error) {
+	vals := e.persistable()
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
+	if ext == "yaml" || ext == "yml" {
+		if doc, err := ioutil.ReadFile(file); err == nil {
+			b, err := yamledit.Apply(doc, vals)
+			if err == nil {
+				return b, nil
+			}
+			e.log().Warn("config file rewritten without its comments", zap.String("file", file), zap.Error(err))
+		}
+	}
+	return source.Encode(vals, ext)
+}
+
+// persistable returns the settings to write back to the config file.
+// Values an overlay or a source supplied, and secrets not held as a
+// reference or ciphertext, are written as the config file has them, or
+// left out when it has none: sources are written their own values by
+// pendingWrites, and secrets are never written in the clear. e.mu must be
+// held.
+func (e *Enforcer) persistable() map[string]interface{} {
+	fromSource := map[string]bool{}
+	for _, read := range e.read {
+		for _, k := range helm.Leaves(read) {
+			fromSource[strings.ToLower(k)] = true
+		}
+	}
+	vals := map[string]interface{}{}
+	for k, v := range settings(e.v) {
+		if e.fromOverlay(k, v) || fromSource[k] || e.plainSecret(k, v) {
+			base, ok := lookupFold(e.base, k)
+			if !ok {
+				continue
 			}
-			e.v.Set(key, ans)
-			_ = os.Setenv(key, ans)
+			v = base
+		}
+		v, ok := e.rawValue(k, v)
+		if !ok {
+			continue
 		}
+		helm.SetPath(vals, k, v)
+	}
+	return vals
+}
+
+// rawValue returns the value to write back for key while it holds val: the
+// reference, ciphertext or ${...} expression it was resolved from, or val
+// itself. ok is false for a secret that no source held. e.mu must be held.
+func (e *Enforcer) rawValue(key string, val interface{}) (raw interface{}, ok bool) {
+	key = strings.ToLower(key)
+	if x, ok := e.expanded[key]; ok && val == x.value {
+		val = x.raw
+	}
+	if r, ok := e.resolved[key]; ok && reflect.DeepEqual(val, r.value) {
+		return r.raw, r.raw != nil
+	}
+	return val, true
+}
+
+// plainSecret reports whether key is secret and val is not the value it was
+// resolved to from a reference or ciphertext, so that writing it back would
+// put the secret in the clear. e.mu must be held.
+func (e *Enforcer) plainSecret(key string, val interface{}) bool {
+	key = strings.ToLower(key)
+	if !e.secret[key] {
+		return false
 	}
+	r, ok := e.resolved[key]
+	return !ok || r.raw == nil || !reflect.DeepEqual(val, r.value)
+}
+
+func (e *Enforcer) RequireString(key string) {
+	e.ensure(key)
+}
+
+// BindEnv makes the environment variable env supply key, whatever the
+// Enforcer's EnvPrefix.
+func (e *Enforcer) BindEnv(key, env string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	before := settings(e.v)
+	if err := e.v.BindEnv(key, env); err != nil {
+		return err
+	}
+	if _, ok := os.LookupEnv(env); ok {
+		e.setSource(key, "env "+env)
+	}
+	e.commit(before)
+	return nil
 }
 
 func (e *Enforcer) RequireDef(key, def string) {
+	e.mu.Lock()
+	before := settings(e.v)
 	e.v.SetDefault(key, def)
+	e.commit(before)
+	e.mu.Unlock()
+	e.subs.drain()
 	_ = os.Setenv(key, def)
 }
 
+// Get returns the value of key without locking, or nil if it is not set.
+func (e *Enforcer) Get(key string) interface{} {
+	val, _ := e.lookup(key)
+	return val
+}
+
 func (e *Enforcer) GetString(key string) string {
-	if !e.v.IsSet(key) || e.v.Get(key) == nil {
-		if k, exists := os.LookupEnv(key); k == "" || exists == false {
-			ans, err := e.query.Ask("Please provide a value for the following key: "+key, &input.Options{
-				Loop:         true,
-				Required:     true,
-				ValidateFunc: e.Ensure(),
-			})
-			if err != nil {
-				panic(err)
-			}
-			e.v.Set(key, ans)
-			_ = os.Setenv(key, ans)
-			return ans
-		}
+	if val, ok := e.lookup(key); ok && val != nil {
+		return cast.ToString(val)
 	}
-	if k, exists := os.LookupEnv(key); k == "" || exists == false {
-		ans, err := e.query.Ask("Please provide a value for the following key: "+key, &input.Options{
-			Loop:         true,
-			Required:     true,
-			ValidateFunc: e.Ensure(),
-		})
-		if err != nil {
-			panic(err)
-		}
-		e.v.Set(key, ans)
-		_ = os.Setenv(key, ans)
-		return ans
-	}
-	return e.v.GetString(key)
+	return cast.ToString(e.ensure(key))
 }
 
 func (e *Enforcer) RequireKeys() {
-	for _, key := range e.v.AllKeys() {
-		if !e.v.IsSet(key) || e.v.Get(key) == "" {
-			if val, exists := os.LookupEnv(key); val != "" && exists == true {
-				e.v.Set(key, val)
-			} else {
-				ans, err := e.query.Ask("Please provide a value for the following key: "+key, &input.Options{
-					Loop:         true,
-					Required:     true,
-					ValidateFunc: e.Ensure(),
-				})
-				if err != nil {
-					panic(err)
-				}
-				e.v.Set(key, ans)
-				_ = os.Setenv(key, ans)
-			}
-		}
+	e.mu.Lock()
+	keys := e.v.AllKeys()
+	e.mu.Unlock()
+	for _, key := range keys {
+		e.ensure(key)
 	}
 }
 
+// Source reports where the value of key came from: a file, an environment
+// variable, a helm --set expression, a default or a prompt.
+func (e *Enforcer) Source(key string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key = strings.ToLower(key)
+	if src, ok := e.origins[key]; ok {
+		return src
+	}
+	// InConfig only knows top-level keys, so nested keys are looked up
+	// under the section they belong to.
+	top := strings.SplitN(key, ".", 2)[0]
+	if (e.v.InConfig(key) || e.v.InConfig(top)) && e.v.ConfigFileUsed() != "" {
+		return e.v.ConfigFileUsed()
+	}
+	env := strings.ToUpper(strings.Replace(key, ".", "_", -1))
+	if e.EnvPrefix != "" {
+		env = strings.ToUpper(e.EnvPrefix) + "_" + env
+	}
+	if _, ok := os.LookupEnv(env); ok {
+		return "env " + env
+	}
+	if e.v.IsSet(key) {
+		return "default"
+	}
+	return "unset"
+}
+
+func (e *Enforcer) setSource(key, src string) {
+	if e.origins == nil {
+		e.origins = map[string]string{}
+	}
+	e.origins[strings.ToLower(key)] = src
+}
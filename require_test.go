@@ -0,0 +1,107 @@
+package require
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gofunct/require/source"
+)
+
+// writeFiles creates files, keyed by their path relative to a new temporary
+// directory, and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "require")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// setenv sets an environment variable for the rest of the test.
+func setenv(t *testing.T, key, val string) {
+	t.Helper()
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, val)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestUpdateConfigsEditsYAMLInPlace(t *testing.T) {
+	doc := "# defaults\nbase: &b\n  host: a # shared\nuse:\n  <<: *b\n  y: 2\nname: app\n"
+	file := filepath.Join(writeFiles(t, map[string]string{"app.yaml": doc}), "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	e.Requirements = []string{"name"}
+	initContext(t, e)
+	e.Set("name", "web")
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# defaults\nbase: &b\n  host: a # shared\nuse:\n  <<: *b\n  y: 2\nname: web\n"
+	if got := readFile(t, file); got != want {
+		t.Errorf("config file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUpdateConfigsLeavesSourceValuesAndSecretsOut(t *testing.T) {
+	file := filepath.Join(writeFiles(t, map[string]string{
+		"app.yaml": "name: app\ndb:\n  host: localhost\n",
+	}), "app.yaml")
+	src := &memSource{vals: map[string]interface{}{
+		"db":  map[string]interface{}{"host": "remotehost", "password": "old"},
+		"api": map[string]interface{}{"url": "https://api"},
+	}}
+	e := NewEnforcer(WithConfigFile(file))
+	e.Sources = []source.Source{src}
+	e.Requirements = []string{"name"}
+	e.RequireSecret("db.password")
+	e.RequireSecret("api.token")
+	initContext(t, e)
+	if got := e.GetString("db.host"); got != "remotehost" {
+		t.Fatalf("db.host = %q, want the source's remotehost", got)
+	}
+	e.Set("name", "web")
+	e.Set("api.url", "https://api2")
+	e.Set("db.password", "hunter2")
+	e.Set("api.token", "t0ken")
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, file), "name: web\ndb:\n  host: localhost\n"; got != want {
+		t.Errorf("config file =\n%s\nwant\n%s", got, want)
+	}
+	want := map[string]interface{}{
+		"db":  map[string]interface{}{"host": "remotehost", "password": "old"},
+		"api": map[string]interface{}{"url": "https://api2"},
+	}
+	if !reflect.DeepEqual(src.vals, want) {
+		t.Errorf("source was written %v, want %v", src.vals, want)
+	}
+}
@@ -0,0 +1,54 @@
+package secret
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache remembers the secrets a Provider returns for TTL, so a secret
+// referenced by several keys or re-read on reload is only fetched once.
+type Cache struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	val     string
+	expires time.Time
+}
+
+// NewCache wraps p in a Cache that keeps secrets for ttl.
+func NewCache(p Provider, ttl time.Duration) *Cache {
+	return &Cache{Provider: p, TTL: ttl}
+}
+
+func (c *Cache) Get(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[name]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.val, nil
+	}
+	c.mu.Unlock()
+	val, err := c.Provider.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]entry{}
+	}
+	c.entries[name] = entry{val: val, expires: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+	return val, nil
+}
+
+// Purge forgets every cached secret.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,57 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mgutz/str"
+)
+
+// Command runs an external helper to look up a secret, such as
+// "pass show {{.name}}" or
+// "gcloud secrets versions access latest --secret={{.name}}". The command is
+// a text/template given the secret name as .name, and the secret is its
+// standard output with trailing newlines removed.
+type Command struct {
+	Cmd     string
+	Timeout time.Duration
+}
+
+func (c *Command) Get(ctx context.Context, name string) (string, error) {
+	tpl, err := template.New("secret").Parse(c.Cmd)
+	if err != nil {
+		return "", err
+	}
+	var line strings.Builder
+	if err := tpl.Execute(&line, map[string]interface{}{"name": name}); err != nil {
+		return "", err
+	}
+	argv := str.ToArgv(line.String())
+	if len(argv) == 0 {
+		return "", errors.New("empty secret command")
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	// Output is captured rather than echoed, since it is a secret.
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", errors.New("secret command " + argv[0] + " failed: " + strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(out.String(), "\r\n"), nil
+}
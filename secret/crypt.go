@@ -0,0 +1,163 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// KeyEnv and KeyFileEnv name the environment variables LoadKey reads the
+// encryption key, or the path of a file holding it, from.
+const (
+	KeyEnv     = "REQUIRE_SECRET_KEY"
+	KeyFileEnv = "REQUIRE_SECRET_KEY_FILE"
+)
+
+// Key is a 256-bit key used to seal and open secrets. Keys are random
+// bytes, made by GenerateKey and stored base64 encoded, as String writes
+// them, in a key file or $REQUIRE_SECRET_KEY.
+type Key [32]byte
+
+// GenerateKey returns a new random Key.
+func GenerateKey() (Key, error) {
+	var k Key
+	if _, err := io.ReadFull(rand.Reader, k[:]); err != nil {
+		return Key{}, err
+	}
+	return k, nil
+}
+
+// ParseKey decodes a Key written by String.
+func ParseKey(s string) (Key, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != len(Key{}) {
+		return Key{}, errors.New("a secret key must be 32 random bytes, base64 encoded, as require keygen writes it")
+	}
+	var k Key
+	copy(k[:], b)
+	return k, nil
+}
+
+// String returns k base64 encoded.
+func (k Key) String() string {
+	return base64.StdEncoding.EncodeToString(k[:])
+}
+
+// WriteKeyFile generates a Key and writes it to a new file at path that
+// only its owner can read.
+func WriteKeyFile(path string) (Key, error) {
+	k, err := GenerateKey()
+	if err != nil {
+		return Key{}, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return Key{}, err
+	}
+	if _, err := f.WriteString(k.String() + "\n"); err != nil {
+		f.Close()
+		return Key{}, err
+	}
+	return k, f.Close()
+}
+
+// LegacyKey derives a Key from a passphrase, as keys were made before they
+// were generated.
+//
+// Deprecated: a passphrase hashed without a salt falls to a dictionary
+// attack. LegacyKey is only for rotating values sealed that way onto a
+// generated key.
+func LegacyKey(passphrase string) Key {
+	return Key(sha256.Sum256([]byte(passphrase)))
+}
+
+// LoadKey reads the key from $REQUIRE_SECRET_KEY, or from the file named by
+// $REQUIRE_SECRET_KEY_FILE, or from keyFile when neither is set.
+func LoadKey(keyFile string) (Key, error) {
+	s, from, err := readKey(keyFile)
+	if err != nil {
+		return Key{}, err
+	}
+	k, err := ParseKey(s)
+	if err != nil {
+		return Key{}, errors.New(from + ": " + err.Error())
+	}
+	return k, nil
+}
+
+// LoadLegacyKey is LoadKey for a passphrase, read from the same places,
+// which it passes to LegacyKey.
+func LoadLegacyKey(keyFile string) (Key, error) {
+	s, _, err := readKey(keyFile)
+	if err != nil {
+		return Key{}, err
+	}
+	return LegacyKey(s), nil
+}
+
+// readKey returns the text of the key LoadKey reads and where it read it.
+func readKey(keyFile string) (key, from string, err error) {
+	if k := os.Getenv(KeyEnv); k != "" {
+		return k, KeyEnv, nil
+	}
+	if f := os.Getenv(KeyFileEnv); f != "" {
+		keyFile = f
+	}
+	if keyFile == "" {
+		return "", "", errors.New("no secret key: set " + KeyEnv + " or " + KeyFileEnv)
+	}
+	b, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(string(b)), keyFile, nil
+}
+
+// Seal encrypts plaintext with AES-256-GCM and returns it base64 encoded
+// with its nonce.
+func Seal(key Key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(key Key, sealed string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	out, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt sealed value: wrong key or corrupted data")
+	}
+	return out, nil
+}
+
+func newGCM(key Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
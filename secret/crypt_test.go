@@ -0,0 +1,115 @@
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestKeyFile(t *testing.T) {
+	path := filepath.Join(tempDir(t), "key")
+	k, err := WriteKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+	got, err := LoadKey(path)
+	if err != nil || got != k {
+		t.Errorf("LoadKey = %v, %v; want the written key", got, err)
+	}
+	if _, err := WriteKeyFile(path); err == nil {
+		t.Error("WriteKeyFile replaced an existing key")
+	}
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == k {
+		t.Error("GenerateKey returned the same key twice")
+	}
+}
+
+func TestParseKeyRejectsPassphrases(t *testing.T) {
+	for _, s := range []string{"hunter2", "c2hvcnQ=", ""} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) accepted a key that is not 32 bytes", s)
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	k, _ := GenerateKey()
+	sealed, err := SealValue(k, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("SealValue = %q", sealed)
+	}
+	if got, err := OpenValue(k, sealed); err != nil || got != "hunter2" {
+		t.Errorf("OpenValue = %q, %v", got, err)
+	}
+	wrong, _ := GenerateKey()
+	if _, err := OpenValue(wrong, sealed); err == nil {
+		t.Error("OpenValue succeeded with the wrong key")
+	}
+}
+
+func TestEncryptDecryptRotateText(t *testing.T) {
+	old, _ := GenerateKey()
+	new, _ := GenerateKey()
+	text := []byte("db:\n  password: DEC[a\\]b] # keep\n")
+	enc, err := EncryptText(text, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(enc), "DEC[") || !strings.Contains(string(enc), "# keep") {
+		t.Fatalf("EncryptText = %s", enc)
+	}
+	rot, err := RotateText(enc, old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecryptText(rot, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dec) != string(text) {
+		t.Errorf("round trip = %q, want %q", dec, text)
+	}
+}
+
+func TestLegacyKeyRotatesOntoGeneratedKey(t *testing.T) {
+	path := filepath.Join(tempDir(t), "old")
+	if err := ioutil.WriteFile(path, []byte("passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old, err := LoadLegacyKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != LegacyKey("passphrase") {
+		t.Error("LoadLegacyKey did not derive the key from the passphrase")
+	}
+	if _, err := LoadKey(path); err == nil {
+		t.Error("LoadKey accepted a passphrase")
+	}
+}
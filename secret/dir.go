@@ -0,0 +1,31 @@
+package secret
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir reads secrets from a directory holding one file per secret, the
+// layout Kubernetes and Docker use when mounting secrets, such as
+// /run/secrets/db-password.
+type Dir struct {
+	Path string
+}
+
+func (d *Dir) Get(ctx context.Context, name string) (string, error) {
+	path := filepath.Join(d.Path, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(d.Path, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", ErrNotFound
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
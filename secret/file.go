@@ -0,0 +1,62 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// File reads secrets from a local file holding a JSON object of names to
+// values, encrypted as a whole with Seal.
+type File struct {
+	Path string
+	Key  Key
+}
+
+func (f *File) Get(ctx context.Context, name string) (string, error) {
+	secrets, err := f.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	val, ok := secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return val, nil
+}
+
+// ReadAll decrypts and returns every secret in the file. A file that does
+// not exist holds no secrets.
+func (f *File) ReadAll() (map[string]string, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Open(f.Key, strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// WriteAll encrypts secrets and replaces the file with them.
+func (f *File) WriteAll(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	sealed, err := Seal(f.Key, plain)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.Path, []byte(sealed+"\n"), 0600)
+}
@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Sealed values are written inline in config files as
+// ENC[aes256gcm:<base64>]. Values waiting to be sealed are marked
+// DEC[<plaintext>], with any "]" inside escaped as "\]".
+const (
+	sealedPrefix = "ENC[aes256gcm:"
+	sealedSuffix = "]"
+)
+
+var (
+	sealedRe   = regexp.MustCompile(`ENC\[aes256gcm:([A-Za-z0-9+/=]+)\]`)
+	unsealedRe = regexp.MustCompile(`DEC\[((?:[^\]\\]|\\.)*)\]`)
+)
+
+// IsSealed reports whether val is a sealed value.
+func IsSealed(val interface{}) bool {
+	s, ok := val.(string)
+	return ok && strings.HasPrefix(s, sealedPrefix) && strings.HasSuffix(s, sealedSuffix)
+}
+
+// SealValue encrypts plaintext into an ENC[...] value.
+func SealValue(key Key, plaintext string) (string, error) {
+	sealed, err := Seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + sealed + sealedSuffix, nil
+}
+
+// OpenValue decrypts an ENC[...] value.
+func OpenValue(key Key, val string) (string, error) {
+	plain, err := Open(key, strings.TrimSuffix(strings.TrimPrefix(val, sealedPrefix), sealedSuffix))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// EncryptText seals every DEC[...] value in a config file's text, leaving
+// everything else in the file untouched.
+func EncryptText(text []byte, key Key) ([]byte, error) {
+	return replace(unsealedRe, text, func(inner string) (string, error) {
+		return SealValue(key, unescapeMarker(inner))
+	})
+}
+
+// DecryptText turns every ENC[...] value in a config file's text back into a
+// DEC[...] value so it can be edited and encrypted again.
+func DecryptText(text []byte, key Key) ([]byte, error) {
+	return replace(sealedRe, text, func(inner string) (string, error) {
+		plain, err := Open(key, inner)
+		if err != nil {
+			return "", err
+		}
+		return "DEC[" + escapeMarker(string(plain)) + "]", nil
+	})
+}
+
+// RotateText re-seals every ENC[...] value in a config file's text from the
+// old key to the new one.
+func RotateText(text []byte, old, new Key) ([]byte, error) {
+	return replace(sealedRe, text, func(inner string) (string, error) {
+		plain, err := Open(old, inner)
+		if err != nil {
+			return "", err
+		}
+		return SealValue(new, string(plain))
+	})
+}
+
+func replace(re *regexp.Regexp, text []byte, fn func(inner string) (string, error)) ([]byte, error) {
+	var err error
+	out := re.ReplaceAllFunc(text, func(m []byte) []byte {
+		if err != nil {
+			return m
+		}
+		inner := re.FindSubmatch(m)[1]
+		var s string
+		if s, err = fn(string(inner)); err != nil {
+			return m
+		}
+		return []byte(s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func escapeMarker(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `]`, `\]`).Replace(s)
+}
+
+func unescapeMarker(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\]`, `]`).Replace(s)
+}
@@ -0,0 +1,45 @@
+// Package secret resolves sensitive config values from a secret store
+// instead of plain config files.
+package secret
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Scheme prefixes config values that refer to a secret, as in
+// "secret://db-password".
+const Scheme = "secret://"
+
+// ErrNotFound is returned by a Provider that has no secret by that name.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider looks up secrets by name.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Ref returns the secret name a config value refers to, and whether it is a
+// reference at all.
+func Ref(val interface{}) (string, bool) {
+	s, ok := val.(string)
+	if !ok || !strings.HasPrefix(s, Scheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(s, Scheme), true
+}
+
+// Chain asks each provider in turn and returns the first secret found.
+type Chain []Provider
+
+func (c Chain) Get(ctx context.Context, name string) (string, error) {
+	for _, p := range c {
+		val, err := p.Get(ctx, name)
+		if err == ErrNotFound {
+			continue
+		}
+		return val, err
+	}
+	return "", ErrNotFound
+}
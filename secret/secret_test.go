@@ -0,0 +1,92 @@
+package secret
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Get(ctx context.Context, name string) (string, error) {
+	p.calls++
+	if name == "missing" {
+		return "", ErrNotFound
+	}
+	return "value of " + name, nil
+}
+
+func TestRef(t *testing.T) {
+	if name, ok := Ref("secret://db-password"); !ok || name != "db-password" {
+		t.Errorf("Ref = %q, %v", name, ok)
+	}
+	if _, ok := Ref("plain"); ok {
+		t.Error("Ref accepted a plain value")
+	}
+	if _, ok := Ref(5); ok {
+		t.Error("Ref accepted a number")
+	}
+}
+
+func TestCache(t *testing.T) {
+	p := &countingProvider{}
+	c := NewCache(p, time.Hour)
+	for i := 0; i < 3; i++ {
+		if v, err := c.Get(context.Background(), "a"); err != nil || v != "value of a" {
+			t.Fatalf("Get = %q, %v", v, err)
+		}
+	}
+	if p.calls != 1 {
+		t.Errorf("provider called %d times, want 1", p.calls)
+	}
+	c.Purge()
+	c.Get(context.Background(), "a")
+	if p.calls != 2 {
+		t.Errorf("provider called %d times after Purge, want 2", p.calls)
+	}
+	if _, err := c.Get(context.Background(), "missing"); err != ErrNotFound {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := tempDir(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "db-password"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dir{Path: dir}
+	if v, err := d.Get(context.Background(), "db-password"); err != nil || v != "hunter2" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	for _, name := range []string{"missing", "../etc/passwd"} {
+		if _, err := d.Get(context.Background(), name); err != ErrNotFound {
+			t.Errorf("Get(%q) = %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	k, _ := GenerateKey()
+	f := &File{Path: filepath.Join(tempDir(t), "secrets"), Key: k}
+	if _, err := f.Get(context.Background(), "a"); err != ErrNotFound {
+		t.Errorf("Get from a missing file = %v, want ErrNotFound", err)
+	}
+	if err := f.WriteAll(map[string]string{"a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := f.Get(context.Background(), "a"); err != nil || v != "1" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	dir := tempDir(t)
+	c := Chain{&Dir{Path: dir}, &countingProvider{}}
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "value of a" {
+		t.Errorf("Get = %q, %v; want the second provider's value", v, err)
+	}
+}
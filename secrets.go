@@ -0,0 +1,149 @@
+package require
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/secret"
+	"go.uber.org/zap"
+)
+
+// RequireSecret requires key and marks it secret: when no config file sets
+// it, Secrets is asked for a secret of the same name before prompting, and
+// its value is masked wherever the Enforcer reports values.
+func (e *Enforcer) RequireSecret(key string) {
+	e.mu.Lock()
+	e.markSecret(key)
+	e.Requirements = append(e.Requirements, key)
+	e.mu.Unlock()
+}
+
+// IsSecret reports whether key was marked secret with RequireSecret or
+// resolved from a secret:// reference.
+func (e *Enforcer) IsSecret(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.secret[strings.ToLower(key)]
+}
+
+// ResolveSecrets decrypts every ENC[...] value in the config, replaces
+// every secret:// reference with the secret it names and fills in secret
+// requirements that are not set from Secrets. Init calls it after loading
+// sources.
+func (e *Enforcer) ResolveSecrets(ctx context.Context) error {
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	before := settings(e.v)
+	if err := e.resolveSecrets(ctx); err != nil {
+		return err
+	}
+	e.commit(before)
+	return nil
+}
+
+// resolution records a secret resolved from raw, the value its source
+// held, or from no value at all when raw is nil.
+type resolution struct {
+	raw, value interface{}
+}
+
+// resolveSecrets merges resolved secrets into the config layer, recording
+// each in e.resolved. e.mu must be held.
+func (e *Enforcer) resolveSecrets(ctx context.Context) error {
+	resolved := map[string]interface{}{}
+	for k, v := range settings(e.v) {
+		if secret.IsSealed(v) {
+			key, err := e.key()
+			if err != nil {
+				return errors.New("cannot decrypt " + k + ": " + err.Error())
+			}
+			val, err := secret.OpenValue(key, v.(string))
+			if err != nil {
+				return errors.New("cannot decrypt " + k + ": " + err.Error())
+			}
+			helm.SetPath(resolved, k, val)
+			e.markSecret(k)
+			e.recordSecret(k, v, val)
+			e.log().Debug("value decrypted", zap.String("key", k))
+			continue
+		}
+		name, ok := secret.Ref(v)
+		if !ok {
+			continue
+		}
+		if e.Secrets == nil {
+			return errors.New(k + " refers to " + secret.Scheme + name + " but no secret provider is configured")
+		}
+		val, err := e.Secrets.Get(ctx, name)
+		if err != nil {
+			return errors.New("failed to resolve secret " + name + " for " + k + ": " + err.Error())
+		}
+		helm.SetPath(resolved, k, val)
+		e.markSecret(k)
+		e.recordSecret(k, v, val)
+		e.setSource(k, secret.Scheme+name)
+		e.log().Debug("secret resolved", zap.String("key", k), zap.String("secret", name))
+	}
+	if e.Secrets != nil {
+		for k := range e.secret {
+			if v := e.v.Get(k); v != nil && v != "" {
+				continue
+			}
+			val, err := e.Secrets.Get(ctx, k)
+			if err == secret.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return errors.New("failed to look up secret " + k + ": " + err.Error())
+			}
+			helm.SetPath(resolved, k, val)
+			e.recordSecret(k, nil, val)
+			e.setSource(k, secret.Scheme+k)
+			e.log().Debug("secret resolved", zap.String("key", k), zap.String("secret", k))
+		}
+	}
+	if len(resolved) == 0 {
+		return nil
+	}
+	return e.v.MergeConfigMap(resolved)
+}
+
+// recordSecret records that raw, key's value in its source, resolved to
+// val.
+func (e *Enforcer) recordSecret(key string, raw, val interface{}) {
+	if e.resolved == nil {
+		e.resolved = map[string]resolution{}
+	}
+	e.resolved[strings.ToLower(key)] = resolution{raw: raw, value: val}
+}
+
+// key loads the key sealed values are decrypted with the first time one is
+// found. e.mu must be held.
+func (e *Enforcer) key() (secret.Key, error) {
+	if e.sealKey == nil {
+		k, err := secret.LoadKey(e.KeyFile)
+		if err != nil {
+			return secret.Key{}, err
+		}
+		e.sealKey = &k
+	}
+	return *e.sealKey, nil
+}
+
+func (e *Enforcer) markSecret(key string) {
+	if e.secret == nil {
+		e.secret = map[string]bool{}
+	}
+	e.secret[strings.ToLower(key)] = true
+}
+
+// mask hides the value of secret keys. e.mu must be held.
+func (e *Enforcer) mask(key string, val interface{}) interface{} {
+	if e.secret[strings.ToLower(key)] && val != nil && val != "" {
+		return masked
+	}
+	return val
+}
@@ -0,0 +1,108 @@
+package require
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofunct/require/secret"
+	"github.com/gofunct/require/source"
+)
+
+type mapSecrets map[string]string
+
+func (m mapSecrets) Get(ctx context.Context, name string) (string, error) {
+	if v, ok := m[name]; ok {
+		return v, nil
+	}
+	return "", secret.ErrNotFound
+}
+
+func initContext(t *testing.T, e *Enforcer) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := e.InitContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolvedSecretsAreNotPersisted(t *testing.T) {
+	key, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, secret.KeyEnv, key.String())
+	sealed, err := secret.SealValue(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": "db:\n  password: " + sealed + "\n  token: secret://db-token\nname: app\n",
+	})
+	file := filepath.Join(dir, "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	e.Secrets = mapSecrets{"db-token": "t0ken", "api.key": "k3y"}
+	e.RequireSecret("api.key")
+	initContext(t, e)
+	if got := e.GetString("db.password"); got != "hunter2" {
+		t.Errorf("db.password = %q, want hunter2", got)
+	}
+	if got := e.GetString("db.token"); got != "t0ken" {
+		t.Errorf("db.token = %q, want t0ken", got)
+	}
+	e.Set("name", "renamed")
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, file)
+	want := "db:\n  password: " + sealed + "\n  token: secret://db-token\nname: renamed\n"
+	if got != want {
+		t.Errorf("config file =\n%s\nwant\n%s", got, want)
+	}
+	for _, plain := range []string{"hunter2", "t0ken", "k3y"} {
+		if strings.Contains(got, plain) {
+			t.Errorf("config file holds the secret %q", plain)
+		}
+	}
+}
+
+// memSource is a writable source holding values in memory.
+type memSource struct {
+	vals map[string]interface{}
+}
+
+func (m *memSource) Name() string { return "mem" }
+
+func (m *memSource) Read(ctx context.Context) (map[string]interface{}, error) {
+	return m.vals, nil
+}
+
+func (m *memSource) Write(ctx context.Context, vals map[string]interface{}) error {
+	m.vals = vals
+	return nil
+}
+
+func TestResolvedSecretsAreNotWrittenToSources(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.yaml": "name: app\n"})
+	src := &memSource{vals: map[string]interface{}{
+		"db": map[string]interface{}{"token": "secret://db-token"},
+	}}
+	e := NewEnforcer(WithConfigFile(filepath.Join(dir, "app.yaml")))
+	e.Sources = []source.Source{src}
+	e.Secrets = mapSecrets{"db-token": "t0ken"}
+	e.Requirements = []string{"name"}
+	initContext(t, e)
+	if got := e.GetString("db.token"); got != "t0ken" {
+		t.Fatalf("db.token = %q, want t0ken", got)
+	}
+	if err := e.UpdateConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := src.vals["db"].(map[string]interface{})
+	if got := db["token"]; got != "secret://db-token" {
+		t.Errorf("source was written db.token = %v, want the reference", got)
+	}
+}
@@ -0,0 +1,332 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/kube"
+	"github.com/gofunct/require/yamledit"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// Compose reads the environment a docker-compose service's container will
+// receive, from its env_file entries and its environment block, so
+// requirements can be checked against it. Variables are matched to config
+// keys the way an Enforcer with EnvPrefix Prefix looks them up: Keys are
+// matched exactly, and any other variable under the prefix becomes the key
+// its name spells with "_" read as ".".
+type Compose struct {
+	File    string
+	Service string
+	Prefix  string
+	Keys    []string
+}
+
+func NewCompose(file, service string) *Compose {
+	return &Compose{File: file, Service: service}
+}
+
+func (c *Compose) Name() string {
+	return c.File + "#" + c.Service
+}
+
+func (c *Compose) Read(ctx context.Context) (map[string]interface{}, error) {
+	env, err := c.Env()
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]interface{}{}
+	matched := map[string]bool{}
+	for _, key := range c.Keys {
+		name := kube.EnvName(c.Prefix, key)
+		if val, ok := env[name]; ok {
+			helm.SetPath(vals, strings.ToLower(key), val)
+			matched[name] = true
+		}
+	}
+	prefix := ""
+	if c.Prefix != "" {
+		prefix = strings.ToUpper(c.Prefix) + "_"
+	}
+	for name, val := range env {
+		if matched[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		key := strings.ToLower(strings.Replace(strings.TrimPrefix(name, prefix), "_", ".", -1))
+		if _, taken := helm.Lookup(vals, key); !taken {
+			helm.SetPath(vals, key, val)
+		}
+	}
+	return vals, nil
+}
+
+// Env returns the service's environment: its env_file entries in order,
+// overridden by its environment block, with ${VAR} references expanded from
+// the project's .env file and the process environment.
+func (c *Compose) Env() (map[string]string, error) {
+	svc, err := c.service()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(c.File)
+	dotenv, err := readEnvFile(filepath.Join(dir, ".env"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	lookup := func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := dotenv[name]
+		return v, ok
+	}
+	env := map[string]string{}
+	files, err := envFiles(svc["env_file"])
+	if err != nil {
+		return nil, errors.New(c.Name() + ": " + err.Error())
+	}
+	for _, f := range files {
+		if !filepath.IsAbs(f.path) {
+			f.path = filepath.Join(dir, f.path)
+		}
+		vars, err := readEnvFile(f.path)
+		if os.IsNotExist(err) && !f.required {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			env[k] = v
+		}
+	}
+	environment, err := environmentOf(svc["environment"])
+	if err != nil {
+		return nil, errors.New(c.Name() + ": " + err.Error())
+	}
+	for k, v := range environment {
+		if v == nil {
+			// A bare name passes the variable through from the host.
+			if hv, ok := lookup(k); ok {
+				env[k] = hv
+			}
+			continue
+		}
+		env[k] = expand(*v, lookup)
+	}
+	return env, nil
+}
+
+// Write sets vals as variables in the service's environment block, keeping
+// the block's form, a map or a list. The file is edited in place, so its
+// comments and layout survive: a map gets each changed variable set where
+// it stands or added at its end, and a list is replaced as a whole.
+// Variables that already hold their value are left as they are.
+func (c *Compose) Write(ctx context.Context, vals map[string]interface{}) error {
+	b, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		return err
+	}
+	svc, err := c.service()
+	if err != nil {
+		return err
+	}
+	current, err := c.Env()
+	if err != nil {
+		return err
+	}
+	env := map[string]string{}
+	for _, k := range helm.Leaves(vals) {
+		v, _ := helm.Lookup(vals, k)
+		name, val := kube.EnvName(c.Prefix, k), cast.ToString(v)
+		if cur, ok := current[name]; ok && cur == val {
+			// Leave entries that already give the value alone, so
+			// references such as ${VAR:-default} survive.
+			continue
+		}
+		env[name] = strings.Replace(val, "$", "$$", -1)
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	path := []string{"services", c.Service, "environment"}
+	switch block := svc["environment"].(type) {
+	case map[string]interface{}, nil:
+		for _, name := range names {
+			if b, err = yamledit.Set(b, append(path, name), env[name]); err != nil {
+				return errors.New(c.Name() + ": " + err.Error())
+			}
+		}
+	case []interface{}:
+		for _, name := range names {
+			entry := name + "=" + env[name]
+			found := false
+			for j, item := range block {
+				s := cast.ToString(item)
+				if s == name || strings.HasPrefix(s, name+"=") {
+					block[j], found = entry, true
+				}
+			}
+			if !found {
+				block = append(block, entry)
+			}
+		}
+		if b, err = yamledit.Set(b, path, block); err != nil {
+			return errors.New(c.Name() + ": " + err.Error())
+		}
+	default:
+		return errors.New(c.Name() + ": environment is neither a map nor a list")
+	}
+	info, err := os.Stat(c.File)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.File, b, info.Mode())
+}
+
+func (c *Compose) service() (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	services, _ := helm.Normalize(doc).(map[string]interface{})["services"].(map[string]interface{})
+	svc, ok := services[c.Service].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no service " + c.Service + " in " + c.File)
+	}
+	return svc, nil
+}
+
+type envFile struct {
+	path     string
+	required bool
+}
+
+// envFiles accepts env_file as a path, a list of paths or a list of
+// {path, required} entries.
+func envFiles(v interface{}) ([]envFile, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []envFile{{path: t, required: true}}, nil
+	case []interface{}:
+		var files []envFile
+		for _, item := range t {
+			switch e := item.(type) {
+			case string:
+				files = append(files, envFile{path: e, required: true})
+			case map[string]interface{}:
+				f := envFile{path: cast.ToString(e["path"]), required: true}
+				if r, ok := e["required"]; ok {
+					f.required = cast.ToBool(r)
+				}
+				files = append(files, f)
+			default:
+				return nil, errors.New("invalid env_file entry")
+			}
+		}
+		return files, nil
+	}
+	return nil, errors.New("env_file is neither a path nor a list")
+}
+
+// environmentOf accepts environment as a map or a list of NAME=value
+// entries. A nil value is a variable passed through from the host.
+func environmentOf(v interface{}) (map[string]*string, error) {
+	env := map[string]*string{}
+	switch t := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, val := range t {
+			if val == nil {
+				env[k] = nil
+				continue
+			}
+			s := cast.ToString(val)
+			env[k] = &s
+		}
+	case []interface{}:
+		for _, item := range t {
+			kv := strings.SplitN(cast.ToString(item), "=", 2)
+			if len(kv) == 1 {
+				env[kv[0]] = nil
+				continue
+			}
+			env[kv[0]] = &kv[1]
+		}
+	default:
+		return nil, errors.New("environment is neither a map nor a list")
+	}
+	return env, nil
+}
+
+// readEnvFile parses NAME=value lines, skipping blanks and # comments and
+// removing quotes around values.
+func readEnvFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		val := strings.TrimSpace(kv[1])
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		vars[strings.TrimSpace(kv[0])] = val
+	}
+	return vars, sc.Err()
+}
+
+// expand substitutes ${VAR}, ${VAR:-default} and ${VAR-default} as compose
+// does, with $$ standing for a literal $.
+func expand(s string, lookup func(string) (string, bool)) string {
+	const dollar = "\x00"
+	s = strings.Replace(s, "$$", dollar, -1)
+	s = os.Expand(s, func(ref string) string {
+		if i := strings.Index(ref, ":-"); i >= 0 {
+			if v, ok := lookup(ref[:i]); ok && v != "" {
+				return v
+			}
+			return ref[i+2:]
+		}
+		if i := strings.Index(ref, "-"); i >= 0 {
+			if v, ok := lookup(ref[:i]); ok {
+				return v
+			}
+			return ref[i+1:]
+		}
+		v, _ := lookup(ref)
+		return v
+	})
+	return strings.Replace(s, dollar, "$", -1)
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeCompose(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := tempDir(t)
+	for name, body := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "docker-compose.yml")
+}
+
+func TestComposeRead(t *testing.T) {
+	file := writeCompose(t, map[string]string{
+		"docker-compose.yml": "services:\n  app:\n    env_file: app.env\n    environment:\n      APP_DB_HOST: ${DB_HOST:-localhost}\n      APP_DB_PORT: \"5432\"\n      APP_PRICE: $$5\n",
+		"app.env":            "APP_DB_PORT=1\nAPP_NAME=\"web\"\n",
+		".env":               "DB_HOST=db\n",
+	})
+	c := NewCompose(file, "app")
+	c.Prefix = "app"
+	c.Keys = []string{"db.host"}
+	vals, err := c.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := vals["db"].(map[string]interface{})
+	if db["host"] != "db" || db["port"] != "5432" || vals["name"] != "web" || vals["price"] != "$5" {
+		t.Errorf("Read = %v", vals)
+	}
+}
+
+func TestComposeWriteMap(t *testing.T) {
+	file := writeCompose(t, map[string]string{
+		"docker-compose.yml": "# stack\nservices:\n  app:\n    image: app # pinned below\n    environment:\n      # the database\n      APP_DB_HOST: ${DB_HOST:-db}\n      APP_DB_PORT: \"5432\"\n  other:\n    image: other\n",
+	})
+	c := NewCompose(file, "app")
+	c.Prefix = "app"
+	err := c.Write(context.Background(), map[string]interface{}{
+		"db":   map[string]interface{}{"host": "db", "port": "6543"},
+		"name": "web",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# stack\nservices:\n  app:\n    image: app # pinned below\n    environment:\n      # the database\n      APP_DB_HOST: ${DB_HOST:-db}\n      APP_DB_PORT: \"6543\"\n      APP_NAME: web\n  other:\n    image: other\n"
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("compose file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestComposeWriteList(t *testing.T) {
+	file := writeCompose(t, map[string]string{
+		"docker-compose.yml": "services:\n  app:\n    # settings\n    environment:\n      - APP_DB_PORT=5432\n      - APP_DEBUG\n",
+	})
+	c := NewCompose(file, "app")
+	c.Prefix = "app"
+	if err := c.Write(context.Background(), map[string]interface{}{"db": map[string]interface{}{"port": 1}, "name": "a$b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "services:\n  app:\n    # settings\n    environment:\n      - APP_DB_PORT=1\n      - APP_DEBUG\n      - APP_NAME=a$$b\n"
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("compose file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestComposeWriteAddsEnvironment(t *testing.T) {
+	file := writeCompose(t, map[string]string{
+		"docker-compose.yml": "services:\n  app:\n    image: app\n",
+	})
+	c := NewCompose(file, "app")
+	if err := c.Write(context.Background(), map[string]interface{}{"port": "80"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "services:\n  app:\n    image: app\n    environment:\n      PORT: \"80\"\n"
+	if got := readTestFile(t, file); got != want {
+		t.Errorf("compose file =\n%s\nwant\n%s", got, want)
+	}
+	if err := NewCompose(file, "missing").Write(context.Background(), map[string]interface{}{"a": 1}); err == nil {
+		t.Error("Write to a missing service succeeded")
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,134 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// Getter fetches a config file with go-getter, so any source go-getter
+// understands can be used: git::, hg::, s3::, http(s)://, file://, archives
+// and local paths verified with ?checksum=. Fetched files are kept in
+// CacheDir and used as a fallback when a later fetch fails.
+type Getter struct {
+	// URL is the go-getter source address.
+	URL string
+	// File is the path of the config file inside the fetched directory or
+	// archive. It can be left empty when URL points at a single file.
+	File string
+	// CacheDir is where fetched sources are stored.
+	CacheDir string
+	// Pwd resolves relative local paths. It defaults to the working
+	// directory.
+	Pwd string
+}
+
+// NewGetter returns a Getter that fetches url into cacheDir.
+func NewGetter(url, cacheDir string) *Getter {
+	return &Getter{URL: url, CacheDir: cacheDir}
+}
+
+func (g *Getter) Name() string {
+	if g.File != "" {
+		return g.URL + "//" + g.File
+	}
+	return g.URL
+}
+
+// Read fetches the source and parses the config file in it. If the fetch
+// fails the previously cached copy is read instead, and the fetch error is
+// only returned when there is no cached copy.
+func (g *Getter) Read(ctx context.Context) (map[string]interface{}, error) {
+	dir := g.cachePath()
+	fetchErr := g.fetch(ctx, dir)
+	if fetchErr != nil {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fetchErr
+		}
+	}
+	path, err := g.locate(dir)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFile(path)
+}
+
+// cachePath is the directory the source is fetched into.
+func (g *Getter) cachePath() string {
+	sum := sha256.Sum256([]byte(g.URL))
+	cache := g.CacheDir
+	if cache == "" {
+		cache = filepath.Join(os.TempDir(), "require")
+	}
+	return filepath.Join(cache, hex.EncodeToString(sum[:8]))
+}
+
+// fetch downloads into a fresh directory and only replaces dir once the
+// download has succeeded, so a failed fetch leaves the cache intact.
+func (g *Getter) fetch(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), filepath.Base(dir)+".")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	pwd := g.Pwd
+	if pwd == "" {
+		if pwd, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	// go-getter wants to create the destination itself.
+	dst := filepath.Join(tmp, "src")
+	client := &getter.Client{
+		Ctx:  ctx,
+		Src:  g.URL,
+		Dst:  dst,
+		Pwd:  pwd,
+		Mode: getter.ClientModeAny,
+	}
+	if err := client.Get(); err != nil {
+		return errors.New("failed to fetch " + g.URL + ": " + err.Error())
+	}
+	old := dir + ".old"
+	_ = os.RemoveAll(old)
+	if _, err := os.Stat(dir); err == nil {
+		if err := os.Rename(dir, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(dst, dir); err != nil {
+		_ = os.Rename(old, dir)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// locate finds the config file within the fetched directory.
+func (g *Getter) locate(dir string) (string, error) {
+	if g.File != "" {
+		return filepath.Join(dir, g.File), nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() {
+			files = append(files, info.Name())
+		}
+	}
+	if len(files) != 1 {
+		return "", errors.New(g.URL + " fetched a directory; set File to the config file to read from it")
+	}
+	return filepath.Join(dir, files[0]), nil
+}
@@ -0,0 +1,136 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+const getterConfig = "name: fetched\n"
+
+func configServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(getterConfig))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetterHTTP(t *testing.T) {
+	srv := configServer(t)
+	g := NewGetter(srv.URL+"/config.yaml", tempDir(t))
+	vals, err := g.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals["name"] != "fetched" {
+		t.Errorf("Read = %v", vals)
+	}
+}
+
+func TestGetterChecksum(t *testing.T) {
+	srv := configServer(t)
+	sum := sha256.Sum256([]byte(getterConfig))
+	good := NewGetter(srv.URL+"/config.yaml?checksum=sha256:"+hex.EncodeToString(sum[:]), tempDir(t))
+	if _, err := good.Read(context.Background()); err != nil {
+		t.Errorf("Read with the right checksum: %v", err)
+	}
+	bad := NewGetter(srv.URL+"/config.yaml?checksum=sha256:"+hex.EncodeToString(make([]byte, 32)), tempDir(t))
+	if _, err := bad.Read(context.Background()); err == nil {
+		t.Error("Read with the wrong checksum succeeded")
+	}
+}
+
+func TestGetterFallsBackToCache(t *testing.T) {
+	srv := configServer(t)
+	cache := tempDir(t)
+	g := NewGetter(srv.URL+"/config.yaml", cache)
+	if _, err := g.Read(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	vals, err := g.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read with the server down and a cached copy: %v", err)
+	}
+	if vals["name"] != "fetched" {
+		t.Errorf("Read = %v, want the cached values", vals)
+	}
+	uncached := NewGetter(srv.URL+"/config.yaml", tempDir(t))
+	if _, err := uncached.Read(context.Background()); err == nil {
+		t.Error("Read with the server down and no cached copy succeeded")
+	}
+}
+
+func TestGetterGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := tempDir(t)
+	if err := ioutil.WriteFile(filepath.Join(repo, "config.yaml"), []byte(getterConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(repo, "README"), []byte("configs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "configs"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	g := NewGetter("git::file://"+repo, tempDir(t))
+	if _, err := g.Read(context.Background()); err == nil {
+		t.Error("Read of a repository holding several files succeeded without File")
+	}
+	g.File = "config.yaml"
+	vals, err := g.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals["name"] != "fetched" {
+		t.Errorf("Read = %v", vals)
+	}
+}
+
+func TestIsRemote(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/etc/app":                                false,
+		"configs":                                 false,
+		"https://example.com/app.yaml":            true,
+		"git::https://example.com/cfg":            true,
+		"s3::https://s3.amazonaws.com/b/app.yaml": true,
+		"./app.yaml?checksum=sha256:00":           true,
+		"./configs.tgz?archive=tar.gz":            true,
+	} {
+		if got := IsRemote(path); got != want {
+			t.Errorf("IsRemote(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
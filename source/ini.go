@@ -0,0 +1,153 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// decodeINI parses an INI file. Keys before the first section are top
+// level, and each [section] nests its keys under the section name, with
+// dotted names such as [db.replica] nesting further. Lines starting with ;
+// or # are comments, as is anything after a ; or # that follows whitespace
+// in an unquoted value. Values are strings, as they are for properties
+// files.
+func decodeINI(b []byte) (map[string]interface{}, error) {
+	vals := map[string]interface{}{}
+	section := vals
+	for n, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errors.New("line " + strconv.Itoa(n+1) + ": unterminated section header")
+			}
+			section = iniSection(vals, strings.TrimSpace(line[1:len(line)-1]))
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, errors.New("line " + strconv.Itoa(n+1) + ": expected key = value")
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		val, err := iniValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(n+1) + ": " + err.Error())
+		}
+		m := section
+		if j := strings.LastIndex(key, "."); j >= 0 {
+			m, key = iniSection(section, key[:j]), key[j+1:]
+		}
+		m[key] = val
+	}
+	return vals, nil
+}
+
+// iniSection returns the map in vals at the dotted path name, creating it
+// if need be.
+func iniSection(vals map[string]interface{}, name string) map[string]interface{} {
+	m := vals
+	for _, part := range strings.Split(strings.ToLower(name), ".") {
+		part = strings.TrimSpace(part)
+		next, ok := m[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[part] = next
+		}
+		m = next
+	}
+	return m
+}
+
+// iniValue unquotes a double or single quoted value, or strips a trailing
+// comment from an unquoted one.
+func iniValue(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' {
+		return strconv.Unquote(s)
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i]), nil
+		}
+	}
+	return s, nil
+}
+
+// encodeINI renders vals as an INI file: top level values first, then a
+// section for every map holding values, named by its dotted path. Lists are
+// written comma separated.
+func encodeINI(vals map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	writeINISection(&buf, "", vals)
+	return buf.Bytes()
+}
+
+func writeINISection(buf *bytes.Buffer, name string, vals map[string]interface{}) {
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sections []string
+	header := name != ""
+	for _, k := range keys {
+		if _, ok := vals[k].(map[string]interface{}); ok {
+			sections = append(sections, k)
+			continue
+		}
+		if header {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			buf.WriteString("[" + name + "]\n")
+			header = false
+		}
+		buf.WriteString(k + " = " + iniFormat(vals[k]) + "\n")
+	}
+	for _, k := range sections {
+		path := k
+		if name != "" {
+			path = name + "." + k
+		}
+		writeINISection(buf, path, vals[k].(map[string]interface{}))
+	}
+}
+
+// joinList renders a list as its items separated by commas, the way INI
+// and properties files, which have no lists of their own, write them.
+func joinList(val interface{}) (string, bool) {
+	switch t := val.(type) {
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, v := range t {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, ","), true
+	case []string:
+		return strings.Join(t, ","), true
+	}
+	return "", false
+}
+
+// iniFormat renders val, quoting it when it would not read back unchanged.
+func iniFormat(val interface{}) string {
+	if val == nil {
+		return ""
+	}
+	s, ok := joinList(val)
+	if !ok {
+		s = fmt.Sprint(val)
+	}
+	if s != strings.TrimSpace(s) || strings.ContainsAny(s, ";#\"'\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/kube"
+)
+
+// Kube reads the values of Kubernetes ConfigMaps and Secrets, either from a
+// manifest file such as one rendered by Enforcer.Kubernetes or from a
+// directory a ConfigMap or Secret is mounted at.
+type Kube struct {
+	Path string
+}
+
+func NewKube(path string) *Kube {
+	return &Kube{Path: path}
+}
+
+func (k *Kube) Name() string {
+	return k.Path
+}
+
+func (k *Kube) Read(ctx context.Context) (map[string]interface{}, error) {
+	info, err := os.Stat(k.Path)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]string
+	if info.IsDir() {
+		data, err = kube.ReadDir(k.Path)
+	} else {
+		var b []byte
+		if b, err = ioutil.ReadFile(k.Path); err == nil {
+			data, err = kube.Decode(b)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]interface{}{}
+	for key, val := range data {
+		helm.SetPath(vals, key, val)
+	}
+	return vals, nil
+}
@@ -0,0 +1,159 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrConflict is returned by S3.Write when the object changed since it was
+// last read, so writing would overwrite someone else's update. S3 has no
+// conditional put in the API this package uses, so the check can miss a
+// write that lands between it and the upload; see S3.Write.
+var ErrConflict = errors.New("config object was changed by someone else since it was read")
+
+// S3 reads <ConfigName>.<Ext> from a bucket and prefix in S3 or any S3-compatible
+// object store. Unchanged objects are not downloaded again, and writes are
+// refused if the object is seen to have changed since it was read.
+type S3 struct {
+	Bucket     string
+	Prefix     string
+	ConfigName string
+	Ext        string
+	// Endpoint points the client at an S3-compatible store instead of AWS.
+	// Path-style addressing is used whenever it is set.
+	Endpoint string
+	Region   string
+	// Client overrides the client built from Endpoint and Region.
+	Client *s3.S3
+
+	mu     sync.Mutex
+	etag   string
+	exists bool
+	cache  map[string]interface{}
+}
+
+// NewS3 returns a source for the <name>.<ext> object under prefix in bucket.
+func NewS3(bucket, prefix, name, ext string) *S3 {
+	return &S3{Bucket: bucket, Prefix: prefix, ConfigName: name, Ext: ext}
+}
+
+func (s *S3) Name() string {
+	return "s3://" + s.Bucket + "/" + s.key()
+}
+
+func (s *S3) key() string {
+	return path.Join(s.Prefix, s.ConfigName+"."+s.Ext)
+}
+
+func (s *S3) client() (*s3.S3, error) {
+	if s.Client != nil {
+		return s.Client, nil
+	}
+	cfg := aws.NewConfig()
+	if s.Region != "" {
+		cfg = cfg.WithRegion(s.Region)
+	}
+	if s.Endpoint != "" {
+		cfg = cfg.WithEndpoint(s.Endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.Client = s3.New(sess)
+	return s.Client, nil
+}
+
+// Read downloads and parses the object, or returns the values read last
+// time if its ETag has not changed. A missing object reads as empty.
+func (s *S3) Read(ctx context.Context) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	in := &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(s.key())}
+	if s.cache != nil && s.etag != "" {
+		in.IfNoneMatch = aws.String(s.etag)
+	}
+	out, err := c.GetObjectWithContext(ctx, in)
+	if err != nil {
+		switch statusCode(err) {
+		case http.StatusNotModified:
+			return s.cache, nil
+		case http.StatusNotFound:
+			s.etag, s.exists, s.cache = "", false, map[string]interface{}{}
+			return s.cache, nil
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	b, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := Decode(b, s.Ext)
+	if err != nil {
+		return nil, errors.New("failed to parse " + s.Name() + ": " + err.Error())
+	}
+	s.etag, s.exists, s.cache = aws.StringValue(out.ETag), true, vals
+	return vals, nil
+}
+
+// Write uploads vals, returning ErrConflict if the object was created,
+// changed or deleted since the last Read.
+//
+// The check is a HEAD request comparing the ETag with the one last read,
+// followed by the upload. The S3 API this package is built on has no
+// conditional PUT, so a writer that uploads between the two requests is
+// overwritten without a conflict being reported. The window is one round
+// trip long; callers with many concurrent writers to one object should
+// serialize their writes with a lock of their own.
+func (s *S3) Write(ctx context.Context, vals map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.client()
+	if err != nil {
+		return err
+	}
+	// Encode first so nothing but the upload separates the check from it.
+	b, err := Encode(vals, s.Ext)
+	if err != nil {
+		return err
+	}
+	head, err := c.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(s.key())})
+	switch {
+	case err != nil && statusCode(err) != http.StatusNotFound:
+		return err
+	case err != nil && s.exists, err == nil && aws.StringValue(head.ETag) != s.etag:
+		return ErrConflict
+	}
+	out, err := c.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key()),
+		Body:   bytes.NewReader(b),
+	})
+	if err != nil {
+		return err
+	}
+	s.etag, s.exists, s.cache = aws.StringValue(out.ETag), true, vals
+	return nil
+}
+
+func statusCode(err error) int {
+	if rf, ok := err.(awserr.RequestFailure); ok {
+		return rf.StatusCode()
+	}
+	return 0
+}
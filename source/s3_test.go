@@ -0,0 +1,114 @@
+package source
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// fakeS3 serves objects from memory with the ETag semantics S3 has.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func etag(b []byte) string {
+	sum := md5.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.objects[r.URL.Path]
+	switch r.Method {
+	case "GET", "HEAD":
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", etag(b))
+		if r.Header.Get("If-None-Match") == etag(b) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(b)
+		}
+	case "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		f.objects[r.URL.Path] = b
+		w.Header().Set("ETag", etag(b))
+	}
+}
+
+func (f *fakeS3) put(path, body string) {
+	f.mu.Lock()
+	f.objects[path] = []byte(body)
+	f.mu.Unlock()
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *s3.S3) {
+	f := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	sess := session.Must(session.NewSession(aws.NewConfig().
+		WithEndpoint(srv.URL).
+		WithRegion("us-east-1").
+		WithS3ForcePathStyle(true).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))))
+	return f, s3.New(sess)
+}
+
+func TestS3ReadWrite(t *testing.T) {
+	f, c := newFakeS3(t)
+	f.put("/bucket/app/config.yaml", "name: app\n")
+	s := NewS3("bucket", "app", "config", "yaml")
+	s.Client = c
+	ctx := context.Background()
+	vals, err := s.Read(ctx)
+	if err != nil || vals["name"] != "app" {
+		t.Fatalf("Read = %v, %v", vals, err)
+	}
+	if err := s.Write(ctx, map[string]interface{}{"name": "web"}); err != nil {
+		t.Fatalf("Write after Read: %v", err)
+	}
+	if vals, err = s.Read(ctx); err != nil || vals["name"] != "web" {
+		t.Errorf("Read after Write = %v, %v", vals, err)
+	}
+}
+
+func TestS3WriteConflict(t *testing.T) {
+	f, c := newFakeS3(t)
+	f.put("/bucket/config.yaml", "name: app\n")
+	s := NewS3("bucket", "", "config", "yaml")
+	s.Client = c
+	ctx := context.Background()
+	if _, err := s.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.put("/bucket/config.yaml", "name: theirs\n")
+	if err := s.Write(ctx, map[string]interface{}{"name": "mine"}); err != ErrConflict {
+		t.Errorf("Write over a changed object = %v, want ErrConflict", err)
+	}
+
+	missing := NewS3("bucket", "", "other", "yaml")
+	missing.Client = c
+	if vals, err := missing.Read(ctx); err != nil || len(vals) != 0 {
+		t.Fatalf("Read of a missing object = %v, %v", vals, err)
+	}
+	f.put("/bucket/other.yaml", "name: theirs\n")
+	if err := missing.Write(ctx, map[string]interface{}{"name": "mine"}); err != ErrConflict {
+		t.Errorf("Write over an object created since Read = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package source provides config sources that live outside an Enforcer's
+// local search paths, such as files fetched over the network.
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Source supplies config values from somewhere other than a local config
+// file. Values read from sources are merged over the local file in the
+// order the sources are listed.
+type Source interface {
+	// Name identifies the source when reporting where a value came from.
+	Name() string
+	// Read returns the values the source currently holds.
+	Read(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Writer is a Source that can store values back, as UpdateConfigs does.
+type Writer interface {
+	Source
+	Write(ctx context.Context, vals map[string]interface{}) error
+}
+
+// IsRemote reports whether path is a go-getter source rather than a plain
+// local directory: a URL, a forced getter such as "git::" or "s3::", or a
+// local path carrying a checksum or archive query.
+func IsRemote(path string) bool {
+	if strings.Contains(path, "::") || strings.Contains(path, "://") {
+		return true
+	}
+	return strings.Contains(path, "?checksum=") || strings.Contains(path, "?archive=")
+}
+
+// Exts lists the extensions of the config formats that can be read and
+// written, in the order an Enforcer searches for them.
+var Exts = []string{"yaml", "yml", "json", "toml", "hcl", "properties", "props", "prop", "ini"}
+
+// Supported reports whether ext names a config format in Exts.
+func Supported(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, e := range Exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile parses a config file in any supported format, chosen by the
+// file's extension.
+func ReadFile(path string) (map[string]interface{}, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return nil, errors.New("cannot tell the config format of " + path + " without an extension")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := Decode(b, ext)
+	if err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	return vals, nil
+}
+
+// Encode renders vals in the config format named by ext.
+func Encode(vals map[string]interface{}, ext string) ([]byte, error) {
+	ext = strings.ToLower(ext)
+	if !Supported(ext) {
+		return nil, errors.New("unsupported config format " + strconv.Quote(ext))
+	}
+	if ext == "ini" {
+		return encodeINI(vals), nil
+	}
+	if ext == "properties" || ext == "props" || ext == "prop" {
+		vals = joinLists(vals)
+	}
+	fs := afero.NewMemMapFs()
+	v := viper.New()
+	v.SetFs(fs)
+	if err := v.MergeConfigMap(vals); err != nil {
+		return nil, err
+	}
+	path := "/config." + ext
+	if err := v.WriteConfigAs(path); err != nil {
+		return nil, err
+	}
+	return afero.ReadFile(fs, path)
+}
+
+// joinLists returns a copy of vals with every list joined by joinList,
+// which the properties encoder would otherwise write as an empty value.
+func joinLists(vals map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vals))
+	for k, v := range vals {
+		if m, ok := v.(map[string]interface{}); ok {
+			out[k] = joinLists(m)
+		} else if s, ok := joinList(v); ok {
+			out[k] = s
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Decode parses b in the config format named by ext. Values come back in
+// the same shape whatever the format: HCL blocks, which decode as lists of
+// maps, are turned into plain maps, and repeated blocks into lists.
+func Decode(b []byte, ext string) (map[string]interface{}, error) {
+	ext = strings.ToLower(ext)
+	if !Supported(ext) {
+		return nil, errors.New("unsupported config format " + strconv.Quote(ext))
+	}
+	if ext == "ini" {
+		return decodeINI(b)
+	}
+	v := viper.New()
+	v.SetConfigType(ext)
+	if err := v.ReadConfig(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	return flattenBlocks(v.AllSettings()).(map[string]interface{}), nil
+}
+
+// flattenBlocks replaces the single-element lists of maps HCL decodes a
+// block into with the map itself, so `db { host = "x" }` reads the same as
+// the YAML `db: {host: x}`. Blocks given more than once become a list.
+func flattenBlocks(val interface{}) interface{} {
+	switch t := val.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = flattenBlocks(v)
+		}
+	case []map[string]interface{}:
+		if len(t) == 1 {
+			return flattenBlocks(t[0])
+		}
+		list := make([]interface{}, len(t))
+		for i, m := range t {
+			list[i] = flattenBlocks(m)
+		}
+		return list
+	case []interface{}:
+		for i, v := range t {
+			t[i] = flattenBlocks(v)
+		}
+	}
+	return val
+}
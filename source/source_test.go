@@ -0,0 +1,86 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	// Properties and INI files only hold strings.
+	vals := map[string]interface{}{
+		"name": "app",
+		"db":   map[string]interface{}{"host": "localhost", "port": "5432"},
+	}
+	for _, ext := range Exts {
+		b, err := Encode(vals, ext)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", ext, err)
+			continue
+		}
+		got, err := Decode(b, ext)
+		if err != nil {
+			t.Errorf("Decode(%s): %v\n%s", ext, err, b)
+			continue
+		}
+		if !reflect.DeepEqual(got, vals) {
+			t.Errorf("%s round trip = %v, want %v\n%s", ext, got, vals, b)
+		}
+	}
+}
+
+func TestEncodeListsInFlatFormats(t *testing.T) {
+	vals := map[string]interface{}{
+		"db": map[string]interface{}{"tags": []interface{}{"a", "b"}},
+	}
+	for _, ext := range []string{"properties", "ini"} {
+		b, err := Encode(vals, ext)
+		if err != nil {
+			t.Fatalf("Encode(%s): %v", ext, err)
+		}
+		got, err := Decode(b, ext)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", ext, err)
+		}
+		db, _ := got["db"].(map[string]interface{})
+		if db["tags"] != "a,b" {
+			t.Errorf("%s wrote db.tags as %q, want a,b:\n%s", ext, db["tags"], b)
+		}
+	}
+}
+
+func TestDecodeINI(t *testing.T) {
+	got, err := Decode([]byte("name = app ; the name\n[db]\nhost = \"a b\"\n[db.replica]\nport: 5433\n"), "ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name": "app",
+		"db": map[string]interface{}{
+			"host":    "a b",
+			"replica": map[string]interface{}{"port": "5433"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeHCLBlocks(t *testing.T) {
+	got, err := Decode([]byte("db {\n  host = \"a\"\n}\n"), "hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, ok := got["db"].(map[string]interface{})
+	if !ok || db["host"] != "a" {
+		t.Errorf("Decode = %v, want db.host a", got)
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := Encode(map[string]interface{}{}, "xml"); err == nil {
+		t.Error("Encode accepted xml")
+	}
+	if _, err := Decode(nil, "xml"); err == nil {
+		t.Error("Decode accepted xml")
+	}
+}
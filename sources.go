@@ -0,0 +1,123 @@
+package require
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/source"
+	"go.uber.org/zap"
+)
+
+// remoteSources turns the go-getter URLs in Paths into Getter sources and
+// returns the remaining local paths.
+func (e *Enforcer) remoteSources() []string {
+	local := e.remoteless()
+	for _, p := range e.Paths {
+		if !source.IsRemote(p) {
+			continue
+		}
+		g := source.NewGetter(p, e.CacheDir)
+		if !hasConfigExt(p) {
+			g.File = e.Name + "." + e.Ext
+		}
+		e.Sources = append(e.Sources, g)
+	}
+	return local
+}
+
+// LoadSources reads every source in Sources and merges their values over
+// the local config file, later sources taking precedence. Init calls it, so
+// it only needs to be called directly to refresh remote values.
+func (e *Enforcer) LoadSources(ctx context.Context) error {
+	merged := map[string]interface{}{}
+	origin := map[string]string{}
+	read := make([]map[string]interface{}, len(e.Sources))
+	for i, src := range e.Sources {
+		e.log().Debug("reading config source", zap.String("source", src.Name()))
+		vals, err := src.Read(ctx)
+		if err != nil {
+			e.log().Warn("config source could not be read", zap.String("source", src.Name()), zap.Error(err))
+			return err
+		}
+		vals = helm.Normalize(vals).(map[string]interface{})
+		e.log().Info("config source loaded", zap.String("source", src.Name()), zap.Int("keys", len(helm.Leaves(vals))))
+		read[i] = vals
+		helm.Merge(merged, helm.Merge(map[string]interface{}{}, vals))
+		for _, k := range helm.Leaves(vals) {
+			origin[k] = src.Name()
+		}
+	}
+	defer e.subs.drain()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	before := settings(e.v)
+	e.remote = merged
+	e.read = read
+	if err := e.v.MergeConfigMap(helm.Normalize(merged).(map[string]interface{})); err != nil {
+		return err
+	}
+	for k, name := range origin {
+		e.setSource(k, name)
+	}
+	e.commit(before)
+	return nil
+}
+
+// remoteless returns the entries in Paths that are local directories.
+func (e *Enforcer) remoteless() []string {
+	var local []string
+	for _, p := range e.Paths {
+		if p != "" && !source.IsRemote(p) {
+			local = append(local, p)
+		}
+	}
+	return local
+}
+
+type write struct {
+	src  source.Writer
+	vals map[string]interface{}
+}
+
+// pendingWrites pairs each writable source with the values it supplied,
+// updated to their current values. A secret changed since it was read is
+// written as the source held it, as ExportCompose leaves secrets out, so it
+// never reaches a source in the clear. e.mu must be held.
+func (e *Enforcer) pendingWrites() []write {
+	var writes []write
+	for i, src := range e.Sources {
+		w, ok := src.(source.Writer)
+		if !ok || i >= len(e.read) {
+			continue
+		}
+		vals := map[string]interface{}{}
+		for _, k := range helm.Leaves(e.read[i]) {
+			v := e.v.Get(k)
+			if e.plainSecret(k, v) {
+				v, _ = helm.Lookup(e.read[i], k)
+			}
+			if v, ok := e.rawValue(k, v); ok {
+				helm.SetPath(vals, k, v)
+			}
+		}
+		writes = append(writes, write{src: w, vals: vals})
+	}
+	return writes
+}
+
+func defaultCacheDir() string {
+	if dir := os.Getenv("REQUIRE_CACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", "require")
+}
+
+func hasConfigExt(url string) bool {
+	if i := strings.IndexRune(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	return source.Supported(strings.TrimPrefix(filepath.Ext(url), "."))
+}
@@ -0,0 +1,97 @@
+package require
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// snapshot returns the settings as of the last write.
+func (e *Enforcer) snapshot() map[string]interface{} {
+	if m, ok := e.snap.Load().(map[string]interface{}); ok {
+		return m
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	m := settings(e.v)
+	e.snap.Store(m)
+	return m
+}
+
+// lookup returns the value of key from the snapshot, falling back to viper
+// for keys it cannot know about up front, such as environment variables
+// picked up by AutomaticEnv.
+func (e *Enforcer) lookup(key string) (interface{}, bool) {
+	key = strings.ToLower(key)
+	if val, ok := e.snapshot()[key]; ok {
+		return val, true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.v.IsSet(key) {
+		return nil, false
+	}
+	return e.v.Get(key), true
+}
+
+// commit replaces the read snapshot after a write and queues the resulting
+// changes for subscribers, which are delivered by subs.drain once e.mu is
+// released. e.mu must be held.
+func (e *Enforcer) commit(before map[string]interface{}) Diff {
+	after := settings(e.v)
+	e.snap.Store(after)
+	d := diffSettings(before, after)
+	e.subs.push(d.All())
+	return d
+}
+
+// ensure is ensureContext without a deadline. It panics if the prompt fails.
+func (e *Enforcer) ensure(key string) interface{} {
+	val, err := e.ensureContext(context.Background(), key)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// ensureContext returns the value of key, taking it from the environment or
+// asking for it when it is not set. Prompts are serialized so only one
+// goroutine talks to the terminal at a time, and a key answered while
+// waiting is not asked for again.
+func (e *Enforcer) ensureContext(ctx context.Context, key string) (interface{}, error) {
+	if val, ok := e.lookup(key); ok && val != nil && val != "" {
+		return val, nil
+	}
+	// Deferred first so it runs once e.prompt is released: a subscriber
+	// may well ask for another key.
+	defer e.subs.drain()
+	e.prompt.Lock()
+	defer e.prompt.Unlock()
+	if val, ok := e.lookup(key); ok && val != nil && val != "" {
+		return val, nil
+	}
+	if val, exists := os.LookupEnv(key); val != "" && exists == true {
+		e.log().Info("value taken from environment", zap.String("key", key), zap.String("env", key))
+		e.set(key, val, "env "+key)
+		return val, nil
+	}
+	def := e.commandDefault(ctx, key)
+	e.log().Info("prompting for value", zap.String("key", key), zap.Bool("has_default", def != ""))
+	ans, err := e.ask(ctx, "Please provide a value for the following key: "+key, def)
+	if err != nil {
+		return nil, err
+	}
+	e.set(key, ans, "prompt")
+	_ = os.Setenv(key, ans)
+	return ans, nil
+}
+
+// ask prompts with the Enforcer's timeout settings. e.prompt must be held.
+func (e *Enforcer) ask(ctx context.Context, q, def string) (string, error) {
+	e.dcdr.Timeout = e.PromptTimeout
+	e.dcdr.FailOnTimeout = e.FailOnTimeout
+	e.dcdr.Logger = e.log()
+	return e.dcdr.AskStringContext(ctx, q, def, true)
+}
@@ -0,0 +1,102 @@
+package require
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gofunct/require/audit"
+	"go.uber.org/zap"
+)
+
+// subscriptions delivers changes to the callbacks registered with OnChange.
+// Changes are queued in the order they are made and drained by whichever
+// goroutine finds the queue idle, so callbacks run one at a time, in order,
+// and may themselves change config without deadlocking.
+type subscriptions struct {
+	mu       sync.Mutex
+	subs     map[string][]func(old, new interface{})
+	queue    []Change
+	draining bool
+}
+
+// OnChange registers fn to be called with the old and new value of key
+// whenever it changes, whether from a file reload, a call to Set or an
+// answer to a prompt. Callbacks are delivered one at a time in the order
+// the changes were made.
+func (e *Enforcer) OnChange(key string, fn func(old, new interface{})) {
+	e.subs.mu.Lock()
+	defer e.subs.mu.Unlock()
+	if e.subs.subs == nil {
+		e.subs.subs = map[string][]func(old, new interface{}){}
+	}
+	key = strings.ToLower(key)
+	e.subs.subs[key] = append(e.subs.subs[key], fn)
+}
+
+// Set overrides the value of key at runtime and notifies its subscribers.
+func (e *Enforcer) Set(key string, val interface{}) {
+	e.set(key, val, "set")
+	e.subs.drain()
+}
+
+// set records val for key as coming from src, re-expands the values that
+// refer to it and queues a change for every key whose value changed as a
+// result. Callers deliver the changes with e.subs.drain once they hold no
+// lock a callback could need, e.prompt included.
+func (e *Enforcer) set(key string, val interface{}, src string) {
+	e.mu.Lock()
+	before := settings(e.v)
+	e.v.Set(key, val)
+	e.setSource(key, src)
+	e.log().Debug("value set", zap.String("key", key), zap.String("source", src), e.value("value", key, val))
+	// A reference may name a key still to be prompted for, so failures are
+	// left for Init and Interpolate to report.
+	if err := e.interpolate(); err != nil {
+		e.log().Debug("interpolation failed", zap.String("key", key), zap.Error(err))
+	}
+	e.commit(before)
+	var ev *audit.Event
+	if e.Audit != nil {
+		k := strings.ToLower(key)
+		rec := audit.NewEvent(k, before[k], e.v.Get(k), src, e.secret[k])
+		ev = &rec
+	}
+	e.mu.Unlock()
+	if ev != nil {
+		if err := e.Audit.Record(*ev); err != nil {
+			e.log().Error("audit record failed", zap.String("key", ev.Key), zap.Error(err))
+		}
+	}
+}
+
+// push queues changes for delivery in the order they were committed.
+func (s *subscriptions) push(changes []Change) {
+	s.mu.Lock()
+	if len(s.subs) > 0 {
+		s.queue = append(s.queue, changes...)
+	}
+	s.mu.Unlock()
+}
+
+// drain delivers queued changes unless another goroutine is already doing
+// so, in which case that goroutine picks them up.
+func (s *subscriptions) drain() {
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		return
+	}
+	s.draining = true
+	for len(s.queue) > 0 {
+		c := s.queue[0]
+		s.queue = s.queue[1:]
+		fns := s.subs[c.Key]
+		s.mu.Unlock()
+		for _, fn := range fns {
+			fn(c.Old, c.New)
+		}
+		s.mu.Lock()
+	}
+	s.draining = false
+	s.mu.Unlock()
+}
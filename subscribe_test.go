@@ -0,0 +1,56 @@
+package require
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSubscriberMayRequireDuringInit(t *testing.T) {
+	file := filepath.Join(writeFiles(t, map[string]string{"app.yaml": "name: app\n"}), "app.yaml")
+	setenv(t, "zz_first", "1")
+	setenv(t, "zz_second", "2")
+	e := NewEnforcer(WithConfigFile(file))
+	e.Requirements = []string{"zz_first"}
+	var second interface{}
+	e.OnChange("zz_first", func(old, new interface{}) {
+		// Takes the prompt lock, which Init holds while it sets zz_first.
+		e.RequireString("zz_second")
+		second = e.Get("zz_second")
+	})
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		done <- e.InitContext(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Init deadlocked delivering a change to a subscriber")
+	}
+	if second != "2" {
+		t.Errorf("zz_second = %v, want 2", second)
+	}
+}
+
+func TestOnChangeOrder(t *testing.T) {
+	file := filepath.Join(writeFiles(t, map[string]string{"app.yaml": "n: 0\n"}), "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	var got []interface{}
+	e.OnChange("count", func(old, new interface{}) {
+		got = append(got, new)
+		if new == 1 {
+			e.Set("count", 2)
+		}
+	})
+	e.Set("count", 1)
+	e.Set("other", 1)
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("changes delivered = %v, want [1 2]", got)
+	}
+}
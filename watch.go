@@ -0,0 +1,195 @@
+package require
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gofunct/require/helm"
+	"github.com/gofunct/require/source"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// Watch re-reads the config file whenever it changes and re-validates the
+// Enforcer's requirements against it. A reload that would leave a
+// requirement unmet is rejected: the last good config stays in effect and
+// onChange receives a Diff with Err set. Accepted reloads that change
+// anything are passed to onChange as a Diff of the changed keys. Watching
+// stops when ctx is done.
+func (e *Enforcer) Watch(ctx context.Context, onChange func(Diff)) error {
+	e.mu.Lock()
+	file := e.v.ConfigFileUsed()
+	e.mu.Unlock()
+	if file == "" {
+		return errors.New("no config file was loaded to watch")
+	}
+	file = filepath.Clean(file)
+	current, err := e.readConfig(file)
+	if err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Watch the directory rather than the file so editors and config map
+	// mounts that replace the file by renaming keep being picked up.
+	if err := w.Add(filepath.Dir(file)); err != nil {
+		_ = w.Close()
+		return err
+	}
+	e.log().Debug("watching config file", zap.String("file", file))
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != file || ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				next, err := e.readConfig(file)
+				if err != nil {
+					onChange(Diff{Err: err})
+					continue
+				}
+				if bytes.Equal(next, current) {
+					continue
+				}
+				d, err := e.reload(current, next)
+				e.subs.drain()
+				if err != nil {
+					onChange(Diff{Err: err})
+					continue
+				}
+				current = next
+				if !d.Empty() {
+					onChange(d)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				onChange(Diff{Err: err})
+			}
+		}
+	}()
+	return nil
+}
+
+// readConfig returns the contents of file. Any overlay is applied when it is
+// loaded.
+func (e *Enforcer) readConfig(file string) ([]byte, error) {
+	return ioutil.ReadFile(file)
+}
+
+// reload swaps the config layer from old to next, rolling back to old if
+// the result no longer satisfies the Enforcer's requirements. Readers keep
+// seeing the old settings until the new ones have been validated.
+func (e *Enforcer) reload(old, next []byte) (Diff, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	file := e.v.ConfigFileUsed()
+	before := settings(e.v)
+	if err := e.loadConfig(next); err != nil {
+		_ = e.loadConfig(old)
+		e.log().Warn("config reload rejected", zap.String("file", file), zap.Error(err))
+		return Diff{}, err
+	}
+	err := e.resolve()
+	if err == nil {
+		err = e.validate()
+	}
+	if err != nil {
+		_ = e.loadConfig(old)
+		_ = e.resolve()
+		e.log().Warn("config reload rejected", zap.String("file", file), zap.Error(err))
+		return Diff{}, err
+	}
+	d := e.commit(before)
+	e.log().Info("config reloaded", zap.String("file", file), zap.Int("changes", len(d.All())))
+	e.logChanges("value changed on reload", d)
+	return d, nil
+}
+
+// loadConfig replaces the config layer with the config file contents b,
+// with any overlay applied on top and the values last read from Sources
+// over that, leaving values that were set at runtime, defaults and the
+// environment in place. e.mu must be held.
+func (e *Enforcer) loadConfig(b []byte) error {
+	vals, err := source.Decode(b, e.format())
+	if err != nil {
+		return err
+	}
+	e.base = vals
+	if e.chart != nil || e.overlay != nil {
+		// Helm values keep the case of their keys, which viper loses.
+		raw := map[interface{}]interface{}{}
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+		vals = helm.Normalize(raw).(map[string]interface{})
+		e.base = vals
+		if e.chart != nil {
+			e.chart.Values = vals
+		}
+		if e.overlay != nil {
+			if vals, err = e.applyOverlay(vals); err != nil {
+				return err
+			}
+		}
+	}
+	// Reading an empty YAML document empties the config layer, which
+	// viper offers no other way to replace.
+	e.v.SetConfigType("yaml")
+	if err := e.v.ReadConfig(bytes.NewReader(nil)); err != nil {
+		return err
+	}
+	// The config layer gets its own copy, since viper merges into it in
+	// place and chart.Values must stay as the file has them.
+	if err := e.v.MergeConfigMap(helm.Normalize(vals).(map[string]interface{})); err != nil {
+		return err
+	}
+	if e.remote == nil {
+		return nil
+	}
+	// Values read from sources stay merged over the file, as LoadSources
+	// left them.
+	return e.v.MergeConfigMap(helm.Normalize(e.remote).(map[string]interface{}))
+}
+
+// resolve runs the passes that turn a freshly loaded config layer into
+// settings: renamed keys, secrets and interpolation. e.mu must be held.
+func (e *Enforcer) resolve() error {
+	if err := e.applyRenames(); err != nil {
+		return err
+	}
+	if err := e.resolveSecrets(context.Background()); err != nil {
+		return err
+	}
+	return e.interpolate()
+}
+
+// validate checks the requirements without prompting for anything. e.mu
+// must be held.
+func (e *Enforcer) validate() error {
+	var missing []string
+	for _, key := range e.Requirements {
+		if !e.v.IsSet(key) || e.v.Get(key) == "" || e.v.Get(key) == nil {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("missing required keys: " + strings.Join(missing, ", "))
+	}
+	return e.validateSchema()
+}
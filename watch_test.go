@@ -0,0 +1,73 @@
+package require
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofunct/require/source"
+)
+
+// watch starts watching e's config file, returning the diffs it reports.
+func watch(t *testing.T, e *Enforcer) <-chan Diff {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	diffs := make(chan Diff, 16)
+	if err := e.Watch(ctx, func(d Diff) { diffs <- d }); err != nil {
+		t.Fatal(err)
+	}
+	return diffs
+}
+
+// replaceFile swaps body in as the contents of path with a rename, the way
+// editors and ConfigMap mounts do, so a watcher never reads it half written.
+func replaceFile(t *testing.T, path, body string) {
+	t.Helper()
+	tmp := filepath.Join(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err := ioutil.WriteFile(tmp, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func nextDiff(t *testing.T, diffs <-chan Diff) Diff {
+	t.Helper()
+	select {
+	case d := <-diffs:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload was reported")
+	}
+	return Diff{}
+}
+
+func TestWatchKeepsSourceValues(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.yaml":      "name: app\n",
+		"mount/db.host": "kubehost",
+	})
+	file := filepath.Join(dir, "app.yaml")
+	e := NewEnforcer(WithConfigFile(file))
+	e.Sources = []source.Source{source.NewKube(filepath.Join(dir, "mount"))}
+	e.Requirements = []string{"name", "db.host"}
+	initContext(t, e)
+	diffs := watch(t, e)
+
+	replaceFile(t, file, "name: web\n")
+	d := nextDiff(t, diffs)
+	if d.Err != nil {
+		t.Fatalf("reload rejected: %v", d.Err)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Key != "name" {
+		t.Fatalf("reload changed %v, want only name", d.All())
+	}
+	if got := e.GetString("db.host"); got != "kubehost" {
+		t.Errorf("db.host = %q after the reload, want the source's kubehost", got)
+	}
+}
@@ -0,0 +1,309 @@
+package yamledit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// node is a key in a block mapping and the lines its value spans.
+type node struct {
+	key    string
+	indent int
+	// line is the line of the key and end the last line of its value. The
+	// document's root has line -1.
+	line, end int
+	// valStart and valEnd delimit the value written on the key line,
+	// without its trailing comment.
+	valStart, valEnd int
+	// children are the keys of a block mapping value, and childIndent
+	// their indentation. children is nil for any other value.
+	children    []*node
+	childIndent int
+}
+
+// child returns the key in n's mapping named key, matching it case
+// insensitively when there is no exact match.
+func (n *node) child(key string) *node {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	for _, c := range n.children {
+		if strings.EqualFold(c.key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// step returns how far the first nested mapping under n is indented from
+// its key, or 0 if there is none.
+func (n *node) step() int {
+	for _, c := range n.children {
+		if c.children != nil {
+			return c.childIndent - c.indent
+		}
+		if s := c.step(); s > 0 {
+			return s
+		}
+	}
+	return 0
+}
+
+// parse reads the block mapping at the top of the first document.
+func (ed *editor) parse() (*node, error) {
+	start := 0
+	for i, l := range ed.lines {
+		if skip(l) || strings.HasPrefix(l, "%") {
+			continue
+		}
+		if l == "---" || strings.HasPrefix(l, "--- ") {
+			if rest := strings.TrimSpace(l[3:]); rest != "" && rest[0] != '#' {
+				return nil, errors.New("line " + strconv.Itoa(i+1) + ": content after the document marker is not supported")
+			}
+			start = i + 1
+		}
+		break
+	}
+	stop := len(ed.lines)
+	for i := start; i < len(ed.lines); i++ {
+		if l := ed.lines[i]; l == "---" || l == "..." || strings.HasPrefix(l, "--- ") || strings.HasPrefix(l, "... ") {
+			stop = i
+			break
+		}
+	}
+	root := &node{indent: -1, line: -1}
+	children, end, err := ed.block(start, stop-1)
+	if err != nil {
+		return nil, err
+	}
+	root.children, root.end = children, end
+	if len(children) > 0 {
+		root.childIndent = children[0].indent
+	}
+	return root, nil
+}
+
+// block parses the block mapping in lines from to to, returning its keys
+// and the last line of their values, or from-1 if it holds none.
+func (ed *editor) block(from, to int) ([]*node, int, error) {
+	var nodes []*node
+	indent, last := -1, from-1
+	for i := from; i <= to; i++ {
+		l := ed.lines[i]
+		if skip(l) {
+			continue
+		}
+		in := indentOf(l)
+		if indent < 0 {
+			indent = in
+		}
+		if in != indent {
+			return nil, 0, errors.New("line " + strconv.Itoa(i+1) + ": unexpected indentation")
+		}
+		n, err := parseKey(l)
+		if err != nil {
+			return nil, 0, errors.New("line " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		n.line = i
+		_, val := splitProps(l[n.valStart:n.valEnd])
+		literal := strings.HasPrefix(val, "|") || strings.HasPrefix(val, ">")
+		n.end = ed.spanEnd(i, to, in, literal)
+		if val == "" {
+			if first := ed.firstContent(i+1, n.end); first >= 0 && indentOf(ed.lines[first]) > in && !isSeqItem(ed.lines[first]) {
+				if n.children, _, err = ed.block(i+1, n.end); err != nil {
+					return nil, 0, err
+				}
+				n.childIndent = n.children[0].indent
+			}
+		}
+		nodes = append(nodes, n)
+		last = n.end
+		i = n.end
+	}
+	return nodes, last, nil
+}
+
+// spanEnd returns the last line of the value of the key at line i, which
+// is indented by indent: the last line after it that is indented further,
+// or that is a list item level with it. Comments and blank lines between
+// keys belong to the key that follows them, except inside literal blocks.
+func (ed *editor) spanEnd(i, to, indent int, literal bool) int {
+	end := i
+	for k := i + 1; k <= to; k++ {
+		l := ed.lines[k]
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		in := indentOf(l)
+		if literal && in > indent {
+			end = k
+			continue
+		}
+		if skip(l) {
+			continue
+		}
+		if in > indent || (in == indent && isSeqItem(l)) {
+			end = k
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// parseKey parses a line holding a key, as in `key: value # comment`.
+func parseKey(l string) (*node, error) {
+	in := indentOf(l)
+	s := l[in:]
+	n := &node{indent: in}
+	var colon int
+	switch {
+	case isSeqItem(l), strings.HasPrefix(s, "? "), s[0] == '{', s[0] == '[':
+		return nil, errors.New("only block mappings can be edited")
+	case s[0] == '"' || s[0] == '\'':
+		end := closingQuote(s, 0)
+		if end < 0 {
+			return nil, errors.New("unterminated quoted key")
+		}
+		key, err := unquote(s[:end+1])
+		if err != nil {
+			return nil, err
+		}
+		n.key = key
+		colon = end + 1
+		for colon < len(s) && s[colon] == ' ' {
+			colon++
+		}
+		if colon == len(s) || s[colon] != ':' {
+			return nil, errors.New("expected a colon after the key")
+		}
+	default:
+		colon = -1
+		for j := 0; j < len(s); j++ {
+			if s[j] == ':' && (j+1 == len(s) || s[j+1] == ' ' || s[j+1] == '\t') {
+				colon = j
+				break
+			}
+			if s[j] == '#' && j > 0 && s[j-1] == ' ' {
+				break
+			}
+		}
+		if colon < 0 {
+			return nil, errors.New("expected key: value")
+		}
+		n.key = plainKey(strings.TrimSpace(s[:colon]))
+	}
+	start := in + colon + 1
+	for start < len(l) && (l[start] == ' ' || l[start] == '\t') {
+		start++
+	}
+	end := valueEnd(l, start)
+	if end < start {
+		start = end
+	}
+	n.valStart, n.valEnd = start, end
+	return n, nil
+}
+
+// plainKey returns the key a decoder reads the unquoted key s as, so that
+// keys such as y, off or 0x10 match "true", "false" and "16" in the decoded
+// document.
+func plainKey(s string) string {
+	var k interface{}
+	if err := yaml.Unmarshal([]byte(s), &k); err != nil {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+// valueEnd returns where the value starting at start ends, before any
+// comment and trailing whitespace. Quotes only start a quoted scalar at the
+// beginning of the value or of an item in a flow collection, so the
+// apostrophe in a plain scalar like "it's" is not taken for one.
+func valueEnd(l string, start int) int {
+	end := len(l)
+	for j := start; j < len(l); j++ {
+		c := l[j]
+		if c == '#' && (j == start || l[j-1] == ' ' || l[j-1] == '\t') {
+			end = j
+			break
+		}
+		if (c == '"' || c == '\'') && (j == start || strings.IndexByte("[{,:", prevNonSpace(l, start, j)) >= 0) {
+			if q := closingQuote(l, j); q > 0 {
+				j = q
+			}
+		}
+	}
+	return len(strings.TrimRight(l[:end], " \t"))
+}
+
+// prevNonSpace returns the last character before j that is not a space, or
+// 0 if there is none after start.
+func prevNonSpace(l string, start, j int) byte {
+	for k := j - 1; k >= start; k-- {
+		if l[k] != ' ' && l[k] != '\t' {
+			return l[k]
+		}
+	}
+	return 0
+}
+
+// closingQuote returns the index of the quote that closes the one at
+// s[start], or -1.
+func closingQuote(s string, start int) int {
+	q := s[start]
+	for j := start + 1; j < len(s); j++ {
+		switch {
+		case q == '"' && s[j] == '\\':
+			j++
+		case s[j] == q && q == '\'' && j+1 < len(s) && s[j+1] == '\'':
+			j++
+		case s[j] == q:
+			return j
+		}
+	}
+	return -1
+}
+
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+	return strconv.Unquote(s)
+}
+
+// splitProps splits the anchor and tag, such as "&base" or "!!str", off the
+// front of a value.
+func splitProps(s string) (props, val string) {
+	val = s
+	for strings.HasPrefix(val, "&") || strings.HasPrefix(val, "!") {
+		i := strings.IndexAny(val, " \t")
+		if i < 0 {
+			i = len(val)
+		}
+		props = strings.TrimSpace(props + " " + val[:i])
+		val = strings.TrimSpace(val[i:])
+	}
+	return props, val
+}
+
+func indentOf(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// skip reports whether l is blank or a comment.
+func skip(l string) bool {
+	t := strings.TrimSpace(l)
+	return t == "" || t[0] == '#'
+}
+
+func isSeqItem(l string) bool {
+	t := strings.TrimLeft(l, " ")
+	return t == "-" || strings.HasPrefix(t, "- ")
+}
@@ -0,0 +1,489 @@
+// Package yamledit writes values into a YAML document in place, so the
+// comments, key order, indentation, anchors and quoting of everything that
+// did not change survive, which re-encoding the document loses.
+package yamledit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofunct/require/helm"
+	"gopkg.in/yaml.v2"
+)
+
+// Apply returns doc with the values in vals written into it. Keys that
+// already hold the same value are left alone. A changed scalar is replaced
+// where it stands, in the quoting style it had and keeping its anchor and
+// trailing comment; a changed list or mapping is replaced as a whole. Keys
+// doc lacks are added at the end of their parent mapping. Keys are matched
+// case insensitively, and keys vals does not mention are kept.
+//
+// Apply only edits block mappings. It returns an error for documents it
+// cannot edit, such as one whose top level is not a mapping, rather than
+// produce a document that does not read back as vals.
+func Apply(doc []byte, vals map[string]interface{}) ([]byte, error) {
+	if _, err := decode(doc); err != nil {
+		return nil, err
+	}
+	ed := newEditor(doc)
+	// Keys are written one at a time, decoding the document again after
+	// each, since changing a node another key aliases or merges changes
+	// that key too.
+	var last string
+	for edits := 0; ; edits++ {
+		cur, err := decode(ed.bytes())
+		if err != nil {
+			return nil, errors.New("edit produced invalid YAML: " + err.Error())
+		}
+		path, ok := differs(nil, cur, vals)
+		if !ok {
+			return ed.bytes(), nil
+		}
+		key := strings.Join(path, ".")
+		if key == last || edits > len(helm.Leaves(vals))*2 {
+			return nil, errors.New("could not update " + key + " in place")
+		}
+		if err := ed.set(path, lookupPath(vals, path)); err != nil {
+			return nil, err
+		}
+		last = key
+	}
+}
+
+// Set returns doc with the value at path replaced by val, or added if doc
+// does not have it, as Apply does for a single key.
+func Set(doc []byte, path []string, val interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("empty path")
+	}
+	ed := newEditor(doc)
+	if err := ed.set(path, val); err != nil {
+		return nil, err
+	}
+	return ed.bytes(), nil
+}
+
+// Delete returns doc without the key at path, removing as well any mapping
+// left empty without it, as helm.DeletePath does. It returns an error if
+// path is not a key of a block mapping in doc.
+func Delete(doc []byte, path []string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("empty path")
+	}
+	ed := newEditor(doc)
+	root, err := ed.parse()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*node{root}
+	for _, k := range path {
+		n := nodes[len(nodes)-1].child(k)
+		if n == nil {
+			return nil, errors.New("could not find " + strings.Join(path, ".") + " to delete")
+		}
+		nodes = append(nodes, n)
+	}
+	i := len(nodes) - 1
+	for i > 1 && len(nodes[i-1].children) == 1 {
+		i--
+	}
+	ed.splice(nodes[i].line, nodes[i].end+1, nil)
+	return ed.bytes(), nil
+}
+
+func decode(doc []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(doc, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+	vals, ok := helm.Normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("document is not a mapping")
+	}
+	return vals, nil
+}
+
+// differs returns the path of the first key in want whose value got does
+// not match.
+func differs(path []string, got, want map[string]interface{}) ([]string, bool) {
+	for _, k := range sortedKeys(want) {
+		p := append(path[:len(path):len(path)], k)
+		old, ok := lookup(got, k)
+		if wm, isMap := want[k].(map[string]interface{}); isMap && len(wm) > 0 {
+			om, _ := old.(map[string]interface{})
+			if p, bad := differs(p, om, wm); bad {
+				return p, true
+			}
+			continue
+		}
+		if !ok || !same(old, want[k]) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// same reports whether a and b hold the same value, comparing scalars by
+// their text so an integer read from a file matches the same number given
+// as a string in the environment.
+func same(a, b interface{}) bool {
+	switch at := a.(type) {
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, v := range bt {
+			if av, ok := lookup(at, k); !ok || !same(av, v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bt, ok := toList(b)
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !same(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}, []string:
+		return false
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func toList(v interface{}) ([]interface{}, bool) {
+	switch t := v.(type) {
+	case []interface{}:
+		return t, true
+	case []string:
+		l := make([]interface{}, len(t))
+		for i, s := range t {
+			l[i] = s
+		}
+		return l, true
+	}
+	return nil, false
+}
+
+// lookup returns the value of key in m, matching it case insensitively
+// when there is no exact match.
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editor holds the document as lines, which it edits and re-parses after
+// every change.
+type editor struct {
+	lines []string
+	// newline records whether the document ended with a newline.
+	newline bool
+}
+
+func newEditor(doc []byte) *editor {
+	s := strings.Replace(string(doc), "\r\n", "\n", -1)
+	ed := &editor{newline: s == "" || strings.HasSuffix(s, "\n")}
+	s = strings.TrimSuffix(s, "\n")
+	if s != "" {
+		ed.lines = strings.Split(s, "\n")
+	}
+	return ed
+}
+
+func (ed *editor) bytes() []byte {
+	s := strings.Join(ed.lines, "\n")
+	if ed.newline && s != "" {
+		s += "\n"
+	}
+	return []byte(s)
+}
+
+// set writes val at path, replacing the node there or adding the keys that
+// are missing.
+func (ed *editor) set(path []string, val interface{}) error {
+	root, err := ed.parse()
+	if err != nil {
+		return err
+	}
+	n := root
+	for i, k := range path {
+		child := n.child(k)
+		if child == nil {
+			return ed.insert(n, path[i:], val)
+		}
+		if i == len(path)-1 {
+			return ed.replace(child, val)
+		}
+		if child.children == nil && !ed.empty(child) {
+			// The value is a scalar or a flow collection, which is
+			// replaced whole with the key set in it.
+			cur, err := decode([]byte(strings.Join(ed.lines, "\n")))
+			if err != nil {
+				return err
+			}
+			old, _ := lookupPath(cur, path[:i+1]).(map[string]interface{})
+			m := helm.Merge(map[string]interface{}{}, old)
+			m[path[i+1]] = nested(path[i+2:], val)
+			return ed.replace(child, m)
+		}
+		n = child
+	}
+	return nil
+}
+
+func lookupPath(vals map[string]interface{}, path []string) interface{} {
+	var cur interface{} = vals
+	for _, k := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur, _ = lookup(m, k)
+	}
+	return cur
+}
+
+// nested wraps val in a mapping for each key in path.
+func nested(path []string, val interface{}) interface{} {
+	for i := len(path) - 1; i >= 0; i-- {
+		val = map[string]interface{}{path[i]: val}
+	}
+	return val
+}
+
+// insert adds the key path[0], holding the rest of path and val, at the
+// end of the mapping n.
+func (ed *editor) insert(n *node, path []string, val interface{}) error {
+	indent := n.childIndent
+	if n.children == nil {
+		indent = 0
+		if n.line >= 0 {
+			indent = n.indent + ed.step()
+		}
+	}
+	lines, err := entry(path[0], nested(path[1:], val), indent)
+	if err != nil {
+		return err
+	}
+	ed.splice(n.end+1, n.end+1, lines)
+	return nil
+}
+
+// replace writes val as the value of n.
+func (ed *editor) replace(n *node, val interface{}) error {
+	line := ed.lines[n.line]
+	text := line[n.valStart:n.valEnd]
+	prefix, old := splitProps(text)
+	if prefix != "" {
+		prefix += " "
+	}
+	head, tail := line[:n.valStart], line[n.valEnd:]
+	if n.valStart == n.valEnd {
+		head += " "
+	}
+	if !isCollection(val) {
+		s, err := scalar(val, old)
+		if err != nil {
+			return err
+		}
+		lines := []string{head + prefix + s + tail}
+		if i := strings.Index(s, "\n"); i >= 0 {
+			lines = append([]string{head + prefix + s[:i] + tail}, indentLines(s[i+1:], n.indent)...)
+		}
+		ed.splice(n.line, n.end+1, lines)
+		return nil
+	}
+	if isEmpty(val) || (n.end == n.line && (strings.HasPrefix(old, "{") || strings.HasPrefix(old, "["))) {
+		s, err := flow(val)
+		if err != nil {
+			return err
+		}
+		ed.splice(n.line, n.end+1, []string{head + prefix + s + tail})
+		return nil
+	}
+	// Keep the indentation the old value had, unless it was a list
+	// written level with its key, which a mapping cannot be.
+	indent := n.indent + ed.step()
+	if n.children != nil {
+		indent = n.childIndent
+	} else if first := ed.firstContent(n.line+1, n.end); first >= 0 {
+		if _, isList := toList(val); isList || indentOf(ed.lines[first]) > n.indent {
+			indent = indentOf(ed.lines[first])
+		}
+	}
+	b, err := yaml.Marshal(val)
+	if err != nil {
+		return err
+	}
+	key := strings.TrimRight(head+strings.TrimSpace(prefix), " ") + tail
+	ed.splice(n.line, n.end+1, append([]string{key}, indentLines(string(b), indent)...))
+	return nil
+}
+
+func (ed *editor) splice(from, to int, lines []string) {
+	out := make([]string, 0, len(ed.lines)-(to-from)+len(lines))
+	out = append(out, ed.lines[:from]...)
+	out = append(out, lines...)
+	ed.lines = append(out, ed.lines[to:]...)
+}
+
+// empty reports whether n holds no value at all, which YAML reads as null.
+func (ed *editor) empty(n *node) bool {
+	_, val := splitProps(ed.lines[n.line][n.valStart:n.valEnd])
+	return val == "" && n.end == n.line
+}
+
+// step returns the indentation the document nests mappings by.
+func (ed *editor) step() int {
+	root, err := ed.parse()
+	if err == nil {
+		if s := root.step(); s > 0 {
+			return s
+		}
+	}
+	return 2
+}
+
+// firstContent returns the first line in [from, to] that is neither blank
+// nor a comment, or -1.
+func (ed *editor) firstContent(from, to int) int {
+	for i := from; i <= to && i < len(ed.lines); i++ {
+		if !skip(ed.lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// entry renders key: val as lines indented by indent.
+func entry(key string, val interface{}, indent int) ([]string, error) {
+	b, err := yaml.Marshal(yaml.MapSlice{{Key: key, Value: val}})
+	if err != nil {
+		return nil, err
+	}
+	return indentLines(string(b), indent), nil
+}
+
+func indentLines(s string, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = pad + l
+		}
+	}
+	return lines
+}
+
+func isCollection(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}, []string, yaml.MapSlice:
+		return true
+	}
+	return false
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	case []string:
+		return len(t) == 0
+	}
+	return false
+}
+
+// scalar renders val in the style of old, the text it replaces: single or
+// double quoted strings stay quoted that way, and anything else is written
+// as the YAML encoder would. The encoder may spread a value over several
+// lines, such as a literal block for a string containing newlines, in which
+// case the lines after the first are indented as they would be under a key
+// at the left margin.
+func scalar(val interface{}, old string) (string, error) {
+	s, isString := val.(string)
+	if isString && !strings.Contains(s, "\n") {
+		switch {
+		case strings.HasPrefix(old, "'"):
+			return "'" + strings.Replace(s, "'", "''", -1) + "'", nil
+		case strings.HasPrefix(old, `"`):
+			return strconv.Quote(s), nil
+		}
+	}
+	b, err := yaml.Marshal(yaml.MapSlice{{Key: "k", Value: val}})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(b), "k: "), "\n"), nil
+}
+
+// flow renders a list or mapping on a single line.
+func flow(val interface{}) (string, error) {
+	if l, ok := toList(val); ok {
+		parts := make([]string, len(l))
+		for i, v := range l {
+			s, err := flow(v)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	if m, ok := val.(map[string]interface{}); ok {
+		keys := sortedKeys(m)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			key, err := flowScalar(k)
+			if err != nil {
+				return "", err
+			}
+			s, err := flow(m[k])
+			if err != nil {
+				return "", err
+			}
+			parts[i] = key + ": " + s
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	}
+	return flowScalar(val)
+}
+
+// flowScalar renders val, quoting strings that would otherwise be read as
+// part of the surrounding flow collection.
+func flowScalar(val interface{}) (string, error) {
+	if s, ok := val.(string); ok && (strings.ContainsAny(s, ",[]{}:#\n") || s == "") {
+		return strconv.Quote(s), nil
+	}
+	return scalar(val, "")
+}
@@ -0,0 +1,104 @@
+package yamledit
+
+import "testing"
+
+func TestDelete(t *testing.T) {
+	doc := "# app\ndb:\n  host: a # the host\n  old: 1\n\n# other\nname: x\nlone:\n  only:\n    key: 1\n"
+	for _, tt := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"db", "old"}, "# app\ndb:\n  host: a # the host\n\n# other\nname: x\nlone:\n  only:\n    key: 1\n"},
+		{[]string{"name"}, "# app\ndb:\n  host: a # the host\n  old: 1\n\n# other\nlone:\n  only:\n    key: 1\n"},
+		{[]string{"lone", "only", "key"}, "# app\ndb:\n  host: a # the host\n  old: 1\n\n# other\nname: x\n"},
+		{[]string{"DB", "Host"}, "# app\ndb:\n  old: 1\n\n# other\nname: x\nlone:\n  only:\n    key: 1\n"},
+	} {
+		got, err := Delete([]byte(doc), tt.path)
+		if err != nil {
+			t.Errorf("Delete(%v): %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Delete(%v) =\n%s\nwant\n%s", tt.path, got, tt.want)
+		}
+	}
+	if _, err := Delete([]byte(doc), []string{"db", "missing"}); err == nil {
+		t.Error("Delete of a missing key succeeded")
+	}
+	if _, err := Delete([]byte("db: {old: 1}\n"), []string{"db", "old"}); err == nil {
+		t.Error("Delete of a key in a flow mapping succeeded")
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tt := range []struct {
+		name, doc string
+		vals      map[string]interface{}
+		want      string
+	}{
+		{
+			"keeps comments and quoting",
+			"# app\nname: 'app' # the name\nport: 80\n",
+			map[string]interface{}{"name": "web", "port": 80},
+			"# app\nname: 'web' # the name\nport: 80\n",
+		},
+		{
+			"adds missing keys to their mapping",
+			"db:\n    host: a\nname: x\n",
+			map[string]interface{}{"db": map[string]interface{}{"host": "a", "port": 5432}},
+			"db:\n    host: a\n    port: 5432\nname: x\n",
+		},
+		{
+			"replaces lists",
+			"tags:\n- a\n- b\n",
+			map[string]interface{}{"tags": []interface{}{"c"}},
+			"tags:\n- c\n",
+		},
+		{
+			"keeps anchors",
+			"base: &b\n  host: a\nuse: *b\n",
+			map[string]interface{}{"base": map[string]interface{}{"host": "z"}, "use": map[string]interface{}{"host": "z"}},
+			"base: &b\n  host: z\nuse: *b\n",
+		},
+		{
+			"edits mappings with merge keys",
+			"base: &b\n  host: a\nuse:\n  <<: *b\n  y: 2\n",
+			map[string]interface{}{"use": map[string]interface{}{"host": "z", "true": 3}},
+			"base: &b\n  host: a\nuse:\n  <<: *b\n  y: 3\n  host: z\n",
+		},
+		{
+			"matches keys the decoder does not read as strings",
+			"flags:\n  on: 1\n  n: 2\n",
+			map[string]interface{}{"flags": map[string]interface{}{"true": 5, "false": 2}},
+			"flags:\n  on: 5\n  n: 2\n",
+		},
+	} {
+		got, err := Apply([]byte(tt.doc), tt.vals)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRejectsWhatItCannotEdit(t *testing.T) {
+	if _, err := Apply([]byte("- a\n- b\n"), map[string]interface{}{"a": 1}); err == nil {
+		t.Error("Apply edited a document that is not a mapping")
+	}
+	if _, err := Apply([]byte("a: [1\n"), map[string]interface{}{"a": 1}); err == nil {
+		t.Error("Apply edited invalid YAML")
+	}
+}
+
+func TestSet(t *testing.T) {
+	got, err := Set([]byte("env:\n  A: 1 # keep\n"), []string{"env", "B"}, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "env:\n  A: 1 # keep\n  B: \"2\"\n"; string(got) != want {
+		t.Errorf("Set =\n%s\nwant\n%s", got, want)
+	}
+}